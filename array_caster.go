@@ -15,7 +15,7 @@ func getArrayCaster(s *Scope, fromType, toType reflect.Type) (castFunc, uint8) {
 	case reflect.Array:
 		fromElemType := fromType.Elem()
 		toElemType := toType.Elem()
-		if isRefAble(s, fromElemType, toElemType) {
+		if isRefAble(s, fromElemType, toElemType) && !hasSyncField(s, toElemType) {
 			var arrayTypePtr unsafe.Pointer
 			if fromType.Len() <= toType.Len() {
 				arrayTypePtr = typePtr(fromType)
@@ -51,7 +51,7 @@ func getArrayCaster(s *Scope, fromType, toType reflect.Type) (castFunc, uint8) {
 	case reflect.Slice:
 		fromElemType := fromType.Elem()
 		toElemType := toType.Elem()
-		if isRefAble(s, fromElemType, toElemType) {
+		if isRefAble(s, fromElemType, toElemType) && !hasSyncField(s, toElemType) {
 			toElemTypePtr := typePtr(toElemType)
 			toLen := toType.Len()
 			return func(fromAddr, toAddr unsafe.Pointer) error {
@@ -101,6 +101,8 @@ func getArrayCaster(s *Scope, fromType, toType reflect.Type) (castFunc, uint8) {
 		default:
 			return nil, 0
 		}
+	case reflect.Struct:
+		return getStructToSeqCaster(s, fromType, toType)
 	default:
 		return nil, 0
 	}
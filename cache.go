@@ -71,3 +71,7 @@ func (c *cache) load(idx int) (casterValue, bool) {
 func (c *cache) store(idx int, value casterValue) {
 	c[idx].Store(&value)
 }
+
+func (c *cache) delete(idx int) {
+	c[idx].Store(nil)
+}
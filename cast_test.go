@@ -2,10 +2,15 @@ package cast
 
 import (
 	"fmt"
+	"net/http"
+	"net/url"
 	"reflect"
 	"runtime"
 	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 	"unsafe"
 )
 
@@ -542,3 +547,1208 @@ func BenchmarkStructCast(b *testing.B) {
 		}
 	})
 }
+
+type DefaultsConfig struct {
+	Host    string `cast:"host,default=localhost"`
+	Port    int    `cast:"port,default=8080"`
+	Debug   bool   `cast:"debug,default=true"`
+	Timeout int    `cast:"timeout"`
+}
+
+func (c *DefaultsConfig) SetDefaults() {
+	c.Timeout = 30
+	c.Host = "overwritten by tag default"
+}
+
+func TestDefaults(t *testing.T) {
+	cfg, err := To[DefaultsConfig](map[string]any{"port": 9090})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg != (DefaultsConfig{Host: "localhost", Port: 9090, Debug: true, Timeout: 30}) {
+		t.Fatal(cfg)
+	}
+
+	type From struct {
+		Port    string
+		Timeout int
+	}
+	cfg, err = Cast[From, DefaultsConfig](From{Port: "1", Timeout: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg != (DefaultsConfig{Host: "localhost", Port: 1, Debug: true, Timeout: 2}) {
+		t.Fatal(cfg)
+	}
+
+	type Invalid struct {
+		Port int `cast:"port,default=abc"`
+	}
+	if _, err = To[Invalid](map[string]any{}); err == nil {
+		t.Fatal()
+	}
+}
+
+type zeroer struct {
+	V int
+}
+
+func (z zeroer) IsZero() bool {
+	return z.V < 0
+}
+
+func TestOmitEmpty(t *testing.T) {
+	type S struct {
+		A int             `cast:"a,omitempty"`
+		B *int            `json:"b,omitempty"`
+		C string          `json:"c,omitempty"`
+		D map[string]int  `cast:",omitempty"`
+		E struct{ V int } `cast:"e,omitempty"`
+		F zeroer          `cast:"f,omitzero"`
+		G [1]int          `cast:"g,omitzero"`
+		H int
+	}
+	m, err := To[map[string]any](S{F: zeroer{-1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m, map[string]any{"e": struct{ V int }{}, "H": 0}) {
+		t.Fatal(m)
+	}
+
+	s := NewScope(WithOmitZero())
+	m, err = ToWithScope[map[string]any](s, S{F: zeroer{0}, H: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m, map[string]any{"f": zeroer{0}, "H": 1}) {
+		t.Fatal(m)
+	}
+}
+
+type InlineAddr struct {
+	Host string `cast:"host"`
+	Port int    `cast:"port"`
+}
+
+type InlineBase struct {
+	ID int `cast:"id"`
+}
+
+func TestInlineAndPrefix(t *testing.T) {
+	type S struct {
+		Addr       InlineAddr  `cast:",inline"`
+		Backup     *InlineAddr `cast:",prefix=backup_"`
+		Mirror     InlineAddr  `cast:",prefix=mirror_"`
+		InlineBase `cast:"base,nested"`
+	}
+	m := map[string]any{
+		"host":        "a",
+		"port":        1,
+		"backup_host": "b",
+		"mirror_port": 3,
+		"base":        map[string]any{"id": 4},
+	}
+	s, err := To[S](m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := S{
+		Addr:       InlineAddr{"a", 1},
+		Backup:     &InlineAddr{Host: "b"},
+		Mirror:     InlineAddr{Port: 3},
+		InlineBase: InlineBase{4},
+	}
+	if !reflect.DeepEqual(s, expected) {
+		t.Fatal(s)
+	}
+	m2, err := To[map[string]any](s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m2, map[string]any{
+		"host":        "a",
+		"port":        1,
+		"backup_host": "b",
+		"backup_port": 0,
+		"mirror_host": "",
+		"mirror_port": 3,
+		"base":        InlineBase{4},
+	}) {
+		t.Fatal(m2)
+	}
+}
+
+func TestAnonymousInPointer(t *testing.T) {
+	type Inner struct {
+		InlineBase
+	}
+	type Outer struct {
+		*Inner
+		V int
+	}
+	o, err := To[Outer](map[string]any{"id": 1, "V": 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.Inner == nil || o.ID != 1 || o.V != 2 {
+		t.Fatal(o)
+	}
+}
+
+func TestRemain(t *testing.T) {
+	type Plugin struct {
+		Name  string         `cast:"name"`
+		Extra map[string]any `cast:",remain"`
+	}
+	m := map[string]any{"name": "p", "a": 1, "b": "2"}
+	p, err := To[Plugin](m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(p, Plugin{Name: "p", Extra: map[string]any{"a": 1, "b": "2"}}) {
+		t.Fatal(p)
+	}
+	m2, err := To[map[string]any](p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m, m2) {
+		t.Fatal(m2)
+	}
+	p2, err := DeepCopy(p)
+	if err != nil || !reflect.DeepEqual(p, p2) {
+		t.Fatal(p2, err)
+	}
+
+	type IntPlugin struct {
+		Name  string         `cast:"name"`
+		Extra map[string]int `cast:",remain"`
+	}
+	ip, err := To[IntPlugin](map[IntValue]string{1: "1"})
+	if err != nil || !reflect.DeepEqual(ip.Extra, map[string]int{"value_1": 1}) {
+		t.Fatal(ip, err)
+	}
+	if _, err = To[IntPlugin](map[string]any{"a": "abc"}); err == nil {
+		t.Fatal()
+	}
+}
+
+func TestErrorUnusedAndUnset(t *testing.T) {
+	type DB struct {
+		Host string
+		Port int `cast:",default=3306"`
+	}
+	type Config struct {
+		Port int `cast:"port"`
+		DB   DB  `cast:",inline"`
+	}
+	s := NewScope(WithErrorUnused(), WithErrorUnset())
+	_, err := ToWithScope[Config](s, map[string]any{"prot": 1, "port": 2, "host": "h", "x": 3})
+	if err == nil || err.Error() != "unused keys <prot, x> when casting <map[string]interface {}> to <cast.Config>" {
+		t.Fatal(err)
+	}
+	_, err = ToWithScope[Config](s, map[string]any{})
+	if err == nil || err.Error() != "unset fields <cast.Config.DB.Host, cast.Config.Port>" {
+		t.Fatal(err)
+	}
+	c, err := ToWithScope[Config](s, map[string]any{"port": 2, "host": "h"})
+	if err != nil || c != (Config{2, DB{"h", 3306}}) {
+		t.Fatal(c, err)
+	}
+
+	type From struct {
+		Port  int
+		Other int
+	}
+	_, err = CastWithScope[From, Config](s, From{})
+	if err == nil || err.Error() != "unused fields <cast.From.Other>; unset fields <cast.Config.DB.Host>" {
+		t.Fatal(err)
+	}
+	_, err = CastWithScope[From, Config](NewScope(WithErrorUnset()), From{})
+	if err == nil || err.Error() != "unset fields <cast.Config.DB.Host>" {
+		t.Fatal(err)
+	}
+
+	// 嵌套结构体的问题带上路径，与外层的一起报告
+	type Nested struct {
+		Port int `cast:"port"`
+		DB   DB  `cast:"db"`
+	}
+	_, err = ToWithScope[Nested](s, map[string]any{"db": map[string]any{"hots": "h"}, "prot": 1})
+	if err == nil || err.Error() != "unused keys <db.hots, prot> when casting <map[string]interface {}> to <cast.Nested>; "+
+		"unset fields <cast.Nested.DB.Host, cast.Nested.Port>" {
+		t.Fatal(err)
+	}
+	type FromNested struct {
+		DB struct {
+			Hots string
+		}
+	}
+	_, err = CastWithScope[FromNested, Nested](s, FromNested{})
+	if err == nil || err.Error() != "unused fields <cast.FromNested.DB.Hots>; unset fields <cast.Nested.DB.Host, cast.Nested.Port>" {
+		t.Fatal(err)
+	}
+
+	// Defaulter 赋值了的字段视为已赋值
+	d, err := ToWithScope[WithDefaults](NewScope(WithErrorUnset()), map[string]any{"name": "n"})
+	if err != nil || d.Port != 8080 {
+		t.Fatal(d, err)
+	}
+	_, err = CastWithScope[struct{ Name string }, WithDefaults](NewScope(WithErrorUnset()), struct{ Name string }{"n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+type WithDefaults struct {
+	Name string
+	Port int
+}
+
+func (d *WithDefaults) SetDefaults() {
+	d.Port = 8080
+}
+
+func TestCastWithMetadata(t *testing.T) {
+	type DB struct {
+		Host string
+		Port int `cast:",default=3306"`
+	}
+	type Config struct {
+		Name string
+		DB   DB
+		Tags []string
+	}
+	from := map[string]any{
+		"name": "app",
+		"DB":   map[string]any{"Host": "h", "user": "u"},
+		"x":    1,
+	}
+	c, md, err := CastWithMetadata[map[string]any, Config](defaultScope, from)
+	if err != nil || c.Name != "app" || c.DB != (DB{"h", 3306}) {
+		t.Fatal(c, err)
+	}
+	if !reflect.DeepEqual(md, &Metadata{
+		Keys:   []string{"DB", "DB.Host", "name"},
+		Unused: []string{"DB.user", "x"},
+		Unset:  []string{"Tags"},
+		Folded: []string{"name"},
+	}) {
+		t.Fatalf("%+v", md)
+	}
+
+	type From struct {
+		NAME  string
+		Other int
+	}
+	_, md, err = CastWithMetadata[From, Config](defaultScope, From{NAME: "app"})
+	if err != nil || !reflect.DeepEqual(md, &Metadata{
+		Keys:   []string{"NAME"},
+		Unused: []string{"Other"},
+		Unset:  []string{"DB", "Tags"},
+		Folded: []string{"NAME"},
+	}) {
+		t.Fatalf("%+v %v", md, err)
+	}
+
+	type Unordered struct {
+		Z string
+		Y string
+		A string
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := "z"
+			if i%2 == 0 {
+				key = "y"
+			}
+			_, md, err := CastWithMetadata[map[string]any, Unordered](defaultScope, map[string]any{key: "v"})
+			unset := []string{"A", "Y"}
+			if key == "y" {
+				unset = []string{"A", "Z"}
+			}
+			if err != nil || !reflect.DeepEqual(md.Keys, []string{key}) || !reflect.DeepEqual(md.Unset, unset) {
+				t.Errorf("%+v %v", md, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	// 所有调用共用一个派生作用域，转换器只构建一次
+	ms := defaultScope.forMetadata()
+	if ms != defaultScope.forMetadata() || ms.metadata != nil {
+		t.Fatal("metadata scope is not reused")
+	}
+	if _, ok := ms.casterMap[casterKey{typePtr(reflect.TypeOf(map[string]any{})), typePtr(reflect.TypeOf(Unordered{}))}]; !ok {
+		t.Fatal("caster is not cached")
+	}
+}
+
+func TestKeyPath(t *testing.T) {
+	type Primary struct {
+		Host string
+	}
+	type Config struct {
+		Host    string `cast:"database.primary.host"`
+		Port    int    `cast:"database.primary.port,default=5432"`
+		Replica string `cast:"database.replica"`
+		Name    string `cast:"app.name"`
+	}
+	from := map[string]any{
+		"database": map[string]any{
+			"primary": &Primary{Host: "h1"},
+			"replica": "h2",
+		},
+		"app.name": "demo",
+	}
+	c, err := To[Config](from)
+	if err != nil || c != (Config{"h1", 5432, "h2", "demo"}) {
+		t.Fatal(c, err)
+	}
+	_, err = ToWithScope[Config](NewScope(WithErrorUnused()), from)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := Cast[Config, map[string]any](c)
+	if err != nil || !reflect.DeepEqual(m, map[string]any{
+		"database": map[string]any{
+			"primary": map[string]any{"host": "h1", "port": 5432},
+			"replica": "h2",
+		},
+		"app": map[string]any{"name": "demo"},
+	}) {
+		t.Fatal(m, err)
+	}
+	ms, err := Cast[Config, map[string]string](c)
+	if err != nil || ms["database.primary.port"] != "5432" {
+		t.Fatal(ms, err)
+	}
+
+	type Database struct {
+		Primary *Primary
+	}
+	type From struct {
+		Database Database
+	}
+	c, err = Cast[From, Config](From{Database{&Primary{"h3"}}})
+	if err != nil || c != (Config{Host: "h3", Port: 5432}) {
+		t.Fatal(c, err)
+	}
+
+	// 路径的中间层已存在时，拷贝后再写入，不修改源值里的 map
+	type WithMap struct {
+		Database map[string]any `cast:"database"`
+		Host     string         `cast:"database.primary.host"`
+	}
+	src := WithMap{Database: map[string]any{"port": 1, "primary": map[string]any{"port": 2}}, Host: "h"}
+	m, err = Cast[WithMap, map[string]any](src)
+	if err != nil || !reflect.DeepEqual(m, map[string]any{
+		"database": map[string]any{"port": 1, "primary": map[string]any{"port": 2, "host": "h"}},
+	}) {
+		t.Fatal(m, err)
+	}
+	if !reflect.DeepEqual(src.Database, map[string]any{"port": 1, "primary": map[string]any{"port": 2}}) {
+		t.Fatal(src.Database)
+	}
+}
+
+func TestGet(t *testing.T) {
+	type Server struct {
+		Host string `json:"host"`
+		Port int
+	}
+	data := map[string]any{
+		"servers": []any{
+			map[string]any{"port": "8080"},
+			&Server{Host: "h", Port: 9090},
+		},
+		"a.b":   map[int]string{1: "x"},
+		"empty": nil,
+	}
+	if port, err := Get[int](data, "servers[0].port"); err != nil || port != 8080 {
+		t.Fatal(port, err)
+	}
+	if port, err := Get[string](data, "servers[1].port"); err != nil || port != "9090" {
+		t.Fatal(port, err)
+	}
+	if host, err := Get[string](data, `servers[1]."host"`); err != nil || host != "h" {
+		t.Fatal(host, err)
+	}
+	if v, err := Get[string](data, `["a.b"][1]`); err != nil || v != "x" {
+		t.Fatal(v, err)
+	}
+	if v, err := Get[*int](data, "empty"); err != nil || v != nil {
+		t.Fatal(v, err)
+	}
+	if _, err := Get[int](data, "servers[2].port"); err == nil || err.Error() != "<servers[2]> not found when getting path <servers[2].port>" {
+		t.Fatal(err)
+	}
+	if _, err := Get[int](data, `["a.b"].x`); err == nil || err.Error() != `<["a.b"].x> not found when getting path <["a.b"].x>` {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"servers.", ".servers", "servers[0", "servers[x]", "servers..a", `servers["a]`, "servers[0]a"} {
+		if _, err := Get[int](data, path); err == nil || err.Error() != "invalid path <"+path+">" {
+			t.Fatal(path, err)
+		}
+	}
+}
+
+func TestSet(t *testing.T) {
+	type DB struct {
+		Host string
+		Port int
+	}
+	type Config struct {
+		DB      *DB
+		Servers []DB
+		Labels  map[string]int
+		Extra   any
+	}
+	var c Config
+	for path, value := range map[string]any{
+		"db.host":           "h",
+		"DB.Port":           "5432",
+		"servers[1].port":   80,
+		"servers.0.host":    "s0",
+		"labels.a":          "1",
+		`extra.list[1]."k"`: true,
+	} {
+		if err := Set(&c, path, value); err != nil {
+			t.Fatal(path, err)
+		}
+	}
+	if *c.DB != (DB{"h", 5432}) || !reflect.DeepEqual(c.Servers, []DB{{Host: "s0"}, {Port: 80}}) ||
+		!reflect.DeepEqual(c.Labels, map[string]int{"a": 1}) ||
+		!reflect.DeepEqual(c.Extra, map[string]any{"list": []any{nil, map[string]any{"k": true}}}) {
+		t.Fatalf("%+v", c)
+	}
+
+	m := map[string]any{"a": map[string]any{"b": 1}}
+	if err := Set(&m, "a.c[0]", 2); err != nil || !reflect.DeepEqual(m, map[string]any{"a": map[string]any{"b": 1, "c": []any{2}}}) {
+		t.Fatal(m, err)
+	}
+	if err := Set(&c, "db.host.x", 1); err == nil || err.Error() != "<db.host.x> not settable when setting path <db.host.x>" {
+		t.Fatal(err)
+	}
+	if err := Set(c, "db", 1); err == nil || err.Error() != "set target must be a non-nil pointer, got <cast.Config>" {
+		t.Fatal(err)
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	type DB struct {
+		Hosts []string `json:"hosts"`
+		Port  *int     `cast:"conn.port"`
+	}
+	type Config struct {
+		Name  string
+		DB    DB `json:"db"`
+		Tags  map[string]int
+		Empty []int
+		Extra map[string]any `cast:",remain"`
+	}
+	port := 5432
+	c := Config{
+		Name:  "app",
+		DB:    DB{Hosts: []string{"a", "b"}, Port: &port},
+		Tags:  map[string]int{"x": 1},
+		Extra: map[string]any{"debug": true, "Name": "ignored"},
+	}
+	flat := Flatten(c, "_")
+	want := map[string]any{
+		"Name":         "app",
+		"db_hosts_0":   "a",
+		"db_hosts_1":   "b",
+		"db_conn_port": 5432,
+		"Tags_x":       1,
+		"Empty":        []int(nil),
+		"debug":        true,
+	}
+	if !reflect.DeepEqual(flat, want) {
+		t.Fatal(flat)
+	}
+
+	nested := Unflatten(map[string]string{"db.hosts.0": "a", "db.hosts.1": "b", "db.port": "5432", "a": "x", "a.b": "y"}, "")
+	if !reflect.DeepEqual(nested, map[string]any{
+		"db": map[string]any{"hosts": []any{"a", "b"}, "port": "5432"},
+		"a":  map[string]any{"b": "y"},
+	}) {
+		t.Fatal(nested)
+	}
+	type Out struct {
+		DB struct {
+			Hosts []string
+			Port  int
+		}
+	}
+	out, err := To[Out](nested)
+	if err != nil || out.DB.Port != 5432 || !reflect.DeepEqual(out.DB.Hosts, []string{"a", "b"}) {
+		t.Fatal(out, err)
+	}
+}
+
+func TestDeepMapping(t *testing.T) {
+	type Node struct {
+		Name     string  `json:"name"`
+		Children []*Node `json:"children,omitempty"`
+		Parent   *Node   `json:"parent"`
+	}
+	type Config struct {
+		Root    Node           `json:"root"`
+		At      time.Time      `json:"at"`
+		Host    string         `cast:"db.host"`
+		Raw     []byte         `json:"raw"`
+		Ports   [2]int         `json:"ports"`
+		Labels  map[int]Node   `json:"labels"`
+		Extra   any            `json:"extra"`
+		Nested  map[string]any `json:"nested"`
+		NilPtr  *int           `json:"nil_ptr"`
+		Pointer *int           `json:"pointer"`
+	}
+	at := time.Unix(0, 0)
+	n := 1
+	c := Config{
+		Root:    Node{Name: "r", Children: []*Node{{Name: "c"}}},
+		At:      at,
+		Host:    "h",
+		Raw:     []byte("x"),
+		Ports:   [2]int{1, 2},
+		Labels:  map[int]Node{1: {Name: "l"}},
+		Extra:   &Node{Name: "e"},
+		Nested:  map[string]any{"node": Node{Name: "n"}},
+		Pointer: &n,
+	}
+	m, err := ToMap(&c)
+	want := map[string]any{
+		"root": map[string]any{
+			"name":     "r",
+			"children": []any{map[string]any{"name": "c", "parent": nil}},
+			"parent":   nil,
+		},
+		"at":      at,
+		"db":      map[string]any{"host": "h"},
+		"raw":     []byte("x"),
+		"ports":   []any{1, 2},
+		"labels":  map[string]any{"1": map[string]any{"name": "l", "parent": nil}},
+		"extra":   map[string]any{"name": "e", "parent": nil},
+		"nested":  map[string]any{"node": map[string]any{"name": "n", "parent": nil}},
+		"nil_ptr": nil,
+		"pointer": 1,
+	}
+	if err != nil || !reflect.DeepEqual(m, want) {
+		t.Fatal(m, err)
+	}
+	n = 2
+	if m["pointer"] != 1 {
+		t.Fatal(m["pointer"])
+	}
+
+	// 未开启 WithDeepMapping 时，嵌套的结构体直接装箱
+	m, err = Cast[Config, map[string]any](c)
+	if err != nil || !reflect.DeepEqual(m["root"], c.Root) {
+		t.Fatal(m, err)
+	}
+
+	// ToMapWithScope 保留作用域里的选项与自定义转换器
+	s := NewScope(WithOmitZero(), WithCaster(func(s *Scope, from Node) (any, error) {
+		return from.Name, nil
+	}))
+	m, err = ToMapWithScope(s, Config{Host: "h", Root: Node{Name: "r"}})
+	if err != nil || !reflect.DeepEqual(m, map[string]any{"root": "r", "db": map[string]any{"host": "h"}}) {
+		t.Fatal(m, err)
+	}
+}
+
+func TestKV(t *testing.T) {
+	type Base struct {
+		ID int `json:"id"`
+	}
+	type Item struct {
+		Name string `json:"name"`
+		Base
+		Price float64        `json:"price,omitempty"`
+		Tags  []string       `json:"tags"`
+		Extra map[string]int `cast:",remain"`
+	}
+	item := Item{Name: "a", Base: Base{1}, Tags: []string{"x"}, Extra: map[string]int{"z": 3, "y": 2, "name": 0}}
+	kvs, err := Cast[Item, []KV](item)
+	if err != nil || !reflect.DeepEqual(kvs, []KV{{"name", "a"}, {"tags", []string{"x"}}, {"id", 1}, {"y", 2}, {"z", 3}}) {
+		t.Fatal(kvs, err)
+	}
+	back, err := Cast[[]KV, Item](kvs)
+	if err != nil || !reflect.DeepEqual(back, Item{Name: "a", Base: Base{1}, Tags: []string{"x"}, Extra: map[string]int{"y": 2, "z": 3}}) {
+		t.Fatal(back, err)
+	}
+	m, err := Cast[[]KV, map[string]string]([]KV{{"a", 1}, {"b", true}, {"a", 2}})
+	if err != nil || !reflect.DeepEqual(m, map[string]string{"a": "2", "b": "true"}) {
+		t.Fatal(m, err)
+	}
+}
+
+func TestPositional(t *testing.T) {
+	type Endpoint struct {
+		Host   string `cast:",index=0"`
+		Port   int    `cast:",index=1"`
+		Secure bool   `cast:",index=2"`
+	}
+	e, err := To[Endpoint]([]any{"localhost", "8080", 1, "ignored"})
+	if err != nil || e != (Endpoint{"localhost", 8080, true}) {
+		t.Fatal(e, err)
+	}
+	e, err = Cast[[2]string, Endpoint]([2]string{"h", "80"})
+	if err != nil || e != (Endpoint{"h", 80, false}) {
+		t.Fatal(e, err)
+	}
+	list, err := Cast[Endpoint, []any](Endpoint{"h", 80, true})
+	if err != nil || !reflect.DeepEqual(list, []any{"h", 80, true}) {
+		t.Fatal(list, err)
+	}
+	arr, err := Cast[Endpoint, [2]string](Endpoint{"h", 80, true})
+	if err != nil || arr != [2]string{"h", "80"} {
+		t.Fatal(arr, err)
+	}
+
+	type Row struct {
+		Name  string `cast:",index=2"`
+		ID    int    `cast:",index=0,required"`
+		Score int    `cast:",index=3,default=60"`
+		Note  string
+	}
+	r, err := Cast[[]string, Row]([]string{"1", "x", "tom"})
+	if err != nil || r != (Row{Name: "tom", ID: 1, Score: 60}) {
+		t.Fatal(r, err)
+	}
+	strs, err := Cast[Row, []string](r)
+	if err != nil || !reflect.DeepEqual(strs, []string{"1", "", "tom", "60"}) {
+		t.Fatal(strs, err)
+	}
+	if _, err = Cast[[]string, Row](nil); err == nil || err.Error() != "required field <cast.Row.ID> not match" {
+		t.Fatal(err)
+	}
+	s := NewScope(WithErrorUnused())
+	if _, err = CastWithScope[[]string, Row](s, []string{"1", "x", "tom", "90", "y"}); err == nil ||
+		err.Error() != "unused keys <[1], [4]> when casting <[]string> to <cast.Row>" {
+		t.Fatal(err)
+	}
+
+	// 没有字段配置 index 的结构体默认不按位置转换，开启 WithPositional 后按声明顺序转换
+	type Plain struct {
+		Host   string
+		Port   int
+		Secure bool
+	}
+	if _, err = Cast[[]string, Plain]([]string{"h", "80"}); err == nil {
+		t.Fatal("expected error")
+	}
+	if _, err = Cast[Plain, []string](Plain{"h", 80, false}); err == nil {
+		t.Fatal("expected error")
+	}
+	ps := NewScope(WithPositional())
+	p, err := ToWithScope[Plain](ps, []any{"localhost", 8080, true})
+	if err != nil || p != (Plain{"localhost", 8080, true}) {
+		t.Fatal(p, err)
+	}
+	list, err = CastWithScope[Plain, []any](ps, p)
+	if err != nil || !reflect.DeepEqual(list, []any{"localhost", 8080, true}) {
+		t.Fatal(list, err)
+	}
+	// 配置了 index 时仍只按 index 转换
+	r, err = CastWithScope[[]string, Row](ps, []string{"1", "x", "tom"})
+	if err != nil || r != (Row{Name: "tom", ID: 1, Score: 60}) {
+		t.Fatal(r, err)
+	}
+	type Invalid struct {
+		Host string `cast:",index=x"`
+	}
+	if _, err = Cast[[]string, Invalid]([]string{"h"}); err == nil || err.Error() != "invalid index of field <cast.Invalid.Host>" {
+		t.Fatal(err)
+	}
+	if _, err = ToRecords([]Invalid{{"h"}}, false); err == nil || err.Error() != "invalid index of field <cast.Invalid.Host>" {
+		t.Fatal(err)
+	}
+	type Duplicate struct {
+		Host string `cast:",index=0"`
+		Addr string `cast:",index=0"`
+	}
+	if _, err = Cast[[]string, Duplicate]([]string{"h"}); err == nil || err.Error() != "duplicate index of field <cast.Duplicate.Addr>" {
+		t.Fatal(err)
+	}
+	if _, err = Cast[Duplicate, []string](Duplicate{}); err == nil || err.Error() != "duplicate index of field <cast.Duplicate.Addr>" {
+		t.Fatal(err)
+	}
+}
+
+func TestRecords(t *testing.T) {
+	type Row struct {
+		ID    int    `json:"id"`
+		Name  string `json:"name"`
+		Score int    `cast:"score,default=60"`
+	}
+	rows, err := FromRecords[Row]([][]string{{"name", "ID", "other"}, {"a", "1", "x"}, {"b", "2"}}, true)
+	if err != nil || !reflect.DeepEqual(rows, []Row{{1, "a", 60}, {2, "b", 60}}) {
+		t.Fatal(rows, err)
+	}
+	rows, err = FromRecords[Row]([][]string{{"1", "a", "90"}, {"2", "", ""}}, false)
+	if err != nil || !reflect.DeepEqual(rows, []Row{{1, "a", 90}, {2, "", 60}}) {
+		t.Fatal(rows, err)
+	}
+	_, err = FromRecords[Row]([][]string{{"id", "score"}, {"1", "2"}, {"3", "x"}}, true)
+	if err == nil || !strings.HasPrefix(err.Error(), "row 3, column 2 (score): ") {
+		t.Fatal(err)
+	}
+	maps, err := FromRecords[map[string]int]([][]string{{"a", "b"}, {"1", "2"}}, true)
+	if err != nil || !reflect.DeepEqual(maps, []map[string]int{{"a": 1, "b": 2}}) {
+		t.Fatal(maps, err)
+	}
+
+	records, err := ToRecords(rows, true)
+	if err != nil || !reflect.DeepEqual(records, [][]string{{"id", "name", "score"}, {"1", "a", "90"}, {"2", "", "60"}}) {
+		t.Fatal(records, err)
+	}
+	back, err := FromRecords[Row](records, true)
+	if err != nil || !reflect.DeepEqual(back, rows) {
+		t.Fatal(back, err)
+	}
+}
+
+func TestMultiValueMap(t *testing.T) {
+	type Form struct {
+		Name        string    `json:"name"`
+		Age         int       `json:"age"`
+		Tags        []string  `json:"tags"`
+		IDs         []int     `json:"ids"`
+		At          time.Time `json:"at"`
+		ContentType string
+		Missing     string `json:"missing"`
+	}
+	values := url.Values{
+		"name":         {"tom", "jerry"},
+		"age":          {"18"},
+		"tags":         {"a", "b"},
+		"ids":          {"1", "2"},
+		"at":           {"2025-01-02T03:04:05Z"},
+		"Content-Type": {"text/plain"},
+		"missing":      {},
+	}
+	f, err := Cast[url.Values, Form](values)
+	at, _ := time.Parse(time.RFC3339, "2025-01-02T03:04:05Z")
+	if err != nil || !reflect.DeepEqual(f, Form{"tom", 18, []string{"a", "b"}, []int{1, 2}, at, "text/plain", ""}) {
+		t.Fatal(f, err)
+	}
+	h := http.Header{}
+	h.Set("content-type", "application/json")
+	f, err = Cast[http.Header, Form](h)
+	if err != nil || f.ContentType != "application/json" {
+		t.Fatal(f, err)
+	}
+
+	// 非序列字段优先取第一个值，而不是把所有值按位置转为结构体
+	type Point struct {
+		X int `cast:",index=0"`
+		Y int `cast:",index=1"`
+	}
+	type Shape struct {
+		Center Point
+	}
+	s := NewScope(WithCaster(func(s *Scope, from string) (Point, error) {
+		x, y, _ := strings.Cut(from, ",")
+		return Point{X: len(x), Y: len(y)}, nil
+	}))
+	shape, err := CastWithScope[url.Values, Shape](s, url.Values{"center": {"a,bb", "3"}})
+	if err != nil || shape.Center != (Point{1, 2}) {
+		t.Fatal(shape, err)
+	}
+	shape, err = Cast[url.Values, Shape](url.Values{"center": {"1", "2"}})
+	if err != nil || shape.Center != (Point{1, 2}) {
+		t.Fatal(shape, err)
+	}
+
+	type Query struct {
+		Q    string `json:"q"`
+		Page int    `json:"page,omitempty"`
+		Tags []int  `json:"tags"`
+	}
+	v, err := Cast[Query, url.Values](Query{Q: "go", Tags: []int{1, 2}})
+	if err != nil || v.Encode() != "q=go&tags=1&tags=2" {
+		t.Fatal(v, err)
+	}
+
+	// 只有值为 []string 的 map 视为多值 map
+	r, err := Cast[map[string][]rune, struct{ S string }](map[string][]rune{"S": []rune("hi")})
+	if err != nil || r.S != "hi" {
+		t.Fatal(r, err)
+	}
+
+	// 转为 http.Header 时使用规范的 header key
+	type Headers struct {
+		ContentType string
+		RequestID   string `cast:"x-request-id"`
+		Accept      []string
+	}
+	h, err = Cast[Headers, http.Header](Headers{ContentType: "text/plain", RequestID: "1", Accept: []string{"a", "b"}})
+	if err != nil || h.Get("Content-Type") != "text/plain" || h.Get("X-Request-Id") != "1" || len(h.Values("Accept")) != 2 || len(h) != 3 {
+		t.Fatal(h, err)
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	type DB struct {
+		Host     string
+		Port     int `cast:",default=5432"`
+		MaxConns int
+	}
+	type Config struct {
+		Name    string `json:"name"`
+		DB      DB
+		Cache   *DB
+		Tags    []string
+		Ports   []int
+		Timeout time.Duration
+		Debug   bool `cast:",required"`
+	}
+	environ := []string{
+		"APP_NAME=demo",
+		"APP_DB_HOST=localhost",
+		"APP_DB_MAX_CONNS=10",
+		"APP_TAGS=a, b",
+		"APP_PORTS=80,443",
+		"APP_TIMEOUT=1s",
+		"APP_DEBUG=true",
+		"OTHER_NAME=x",
+	}
+	c, err := FromEnvWithScope[Config](defaultScope, "APP_", environ)
+	if err != nil || !reflect.DeepEqual(c, Config{
+		Name:    "demo",
+		DB:      DB{"localhost", 5432, 10},
+		Tags:    []string{"a", "b"},
+		Ports:   []int{80, 443},
+		Timeout: time.Second,
+		Debug:   true,
+	}) {
+		t.Fatalf("%+v %v", c, err)
+	}
+	_, err = FromEnvWithScope[Config](defaultScope, "APP", []string{"APP_DEBUG=1", "APP_DB_PORT=x"})
+	if err == nil || !strings.HasPrefix(err.Error(), "env <APP_DB_PORT>: ") {
+		t.Fatal(err)
+	}
+	_, err = FromEnvWithScope[Config](defaultScope, "APP", []string{"APP_CACHE_HOST=h"})
+	if err == nil || err.Error() != "required field <cast.Config.Debug> not match" {
+		t.Fatal(err)
+	}
+
+	// 前缀需要以 _ 分隔；没有对应环境变量的嵌套结构体也会设置默认值，结构体指针保持为 nil
+	c, err = FromEnvWithScope[Config](defaultScope, "APP", []string{"APP_DEBUG=1", "APPLE_NAME=x", "APP_CACHES_HOST=h", "ſ_NAME=y"})
+	if err != nil || c.Name != "" || c.DB.Port != 5432 || c.Cache != nil {
+		t.Fatalf("%+v %v", c, err)
+	}
+	c, err = FromEnvWithScope[Config](defaultScope, "s", []string{"ſ_NAME=y", "S_DEBUG=1"})
+	if err != nil || c.Name != "y" {
+		t.Fatalf("%+v %v", c, err)
+	}
+	type Required struct {
+		DB struct {
+			Host string `cast:",required"`
+		}
+	}
+	if _, err = FromEnvWithScope[Required](defaultScope, "APP", nil); err == nil || !strings.HasSuffix(err.Error(), ".Host> not match") {
+		t.Fatal(err)
+	}
+
+	// 按 _ 分隔的字段路径匹配，DBHost 与 DB.Host 不冲突
+	type Conflict struct {
+		DBHost string
+		DB     DB
+	}
+	conflict, err := FromEnvWithScope[Conflict](defaultScope, "APP", []string{"APP_DB_HOST=h", "APP_DBHOST=x"})
+	if err != nil || conflict.DBHost != "x" || conflict.DB.Host != "h" {
+		t.Fatalf("%+v %v", conflict, err)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	type DB struct {
+		Host string
+		Port int
+	}
+	type Config struct {
+		Name   string
+		DB     *DB
+		Tags   []string
+		Labels map[string]string
+		Extra  map[string]any
+	}
+	defaults := Config{Name: "app", DB: &DB{"localhost", 5432}, Tags: []string{"a"}, Labels: map[string]string{"env": "dev"}}
+	file := map[string]any{
+		"db":     map[string]any{"host": "db.local"},
+		"tags":   []string{"b"},
+		"labels": map[string]any{"team": "x"},
+		"extra":  map[string]any{"a": map[string]any{"b": 1}},
+	}
+	flags := &Config{Name: "cli", Extra: map[string]any{"a": map[string]any{"c": 2}}}
+
+	c, err := Merge[Config](defaults, file, nil, flags)
+	if err != nil || !reflect.DeepEqual(c, Config{
+		Name:   "cli",
+		DB:     &DB{"db.local", 5432},
+		Tags:   []string{"b"},
+		Labels: map[string]string{"env": "dev", "team": "x"},
+		Extra:  map[string]any{"a": map[string]any{"b": 1, "c": 2}},
+	}) {
+		t.Fatalf("%+v %v", c, err)
+	}
+	if defaults.DB.Host != "localhost" || len(defaults.Labels) != 1 || len(file["extra"].(map[string]any)["a"].(map[string]any)) != 1 {
+		t.Fatal("sources modified")
+	}
+
+	c, err = MergeWithScope[Config](NewScope(WithMergeAppendSlice()), defaults, file)
+	if err != nil || !reflect.DeepEqual(c.Tags, []string{"a", "b"}) || !reflect.DeepEqual(defaults.Tags, []string{"a"}) {
+		t.Fatal(c, err)
+	}
+
+	m, err := Merge[map[string]any](map[string]any{"db": map[string]any{"host": "h"}}, map[string]any{"db": DB{Port: 1}})
+	if err != nil || !reflect.DeepEqual(m, map[string]any{"db": map[string]any{"host": "h", "Port": 1}}) {
+		t.Fatal(m, err)
+	}
+	c, err = Merge[Config](defaults, map[string]any{"db": map[string]any{"port": "x"}})
+	if err == nil || !strings.HasPrefix(err.Error(), "merge source <1>: field <DB>: field <Port>: ") {
+		t.Fatal(err)
+	}
+}
+
+func TestCastInto(t *testing.T) {
+	type Address struct {
+		City string
+		Zip  string
+	}
+	type User struct {
+		Name    string
+		Age     int
+		Active  bool
+		Address *Address
+		Tags    map[string]string
+	}
+	addr := &Address{"a", "1"}
+	u := User{Name: "tom", Age: 18, Active: true, Address: addr, Tags: map[string]string{"x": "1"}}
+	err := CastInto(defaultScope, map[string]any{"age": "20", "address": map[string]any{"city": "b"}, "tags": map[string]any{"y": 2}}, &u)
+	if err != nil || !reflect.DeepEqual(u, User{"tom", 20, true, &Address{"b", "1"}, map[string]string{"x": "1", "y": "2"}}) || u.Address != addr {
+		t.Fatalf("%+v %v", u, err)
+	}
+
+	type Patch struct {
+		Name   *string
+		Active bool
+		Tags   map[string]string
+	}
+	name := ""
+	if err = CastInto(defaultScope, Patch{Name: &name}, &u); err != nil || u.Name != "" || u.Active || u.Age != 20 || len(u.Tags) != 2 {
+		t.Fatalf("%+v %v", u, err)
+	}
+	if err = CastInto[Patch, User](defaultScope, Patch{}, nil); err != NilPtrErr {
+		t.Fatal(err)
+	}
+
+	// 已有的 map 原地更新，nil map 会新建
+	tags := u.Tags
+	if err = CastInto(defaultScope, map[string]any{"tags": map[string]any{"z": 3}}, &u); err != nil || len(tags) != 3 || tags["z"] != "3" {
+		t.Fatalf("%+v %v", u, err)
+	}
+	u.Tags = nil
+	if err = CastInto(defaultScope, map[string]any{"tags": map[string]any{"z": 3}}, &u); err != nil || !reflect.DeepEqual(u.Tags, map[string]string{"z": "3"}) {
+		t.Fatalf("%+v %v", u, err)
+	}
+
+	// default、required 与以 . 分隔的路径不生效
+	type Config struct {
+		Host string `cast:"db.host"`
+		Port int    `cast:",default=5432"`
+		Name string `cast:",required"`
+	}
+	c := Config{Host: "h"}
+	if err = CastInto(defaultScope, map[string]any{"db": map[string]any{"host": "x"}}, &c); err != nil || c != (Config{Host: "h"}) {
+		t.Fatalf("%+v %v", c, err)
+	}
+	if err = CastInto(defaultScope, map[string]any{"db.host": "x"}, &c); err != nil || c.Host != "x" {
+		t.Fatalf("%+v %v", c, err)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	type Address struct {
+		City string `json:"city"`
+	}
+	type DTO struct {
+		Name    string            `json:"name"`
+		Age     string            `json:"age"`
+		Tags    []string          `json:"tags"`
+		Address *Address          `json:"address"`
+		Extra   map[string]string `json:"extra"`
+		At      time.Time         `json:"at"`
+	}
+	type Model struct {
+		Name    string
+		Age     int
+		Tags    []string
+		Address Address
+		Extra   map[string]any
+		At      time.Time
+	}
+	now := time.Now()
+	dto := DTO{"tom", "18", []string{"a", "b"}, &Address{"sh"}, map[string]string{"k": "1"}, now}
+	model := Model{"tom", 18, []string{"a", "b"}, Address{"sh"}, map[string]any{"k": "1"}, now.UTC()}
+	if ok, err := Equal(dto, model); !ok || err != nil {
+		t.Fatal(ok, err)
+	}
+
+	model.Age = 20
+	model.Tags = []string{"a"}
+	model.Address.City = "bj"
+	model.Extra = map[string]any{"x.y": 2}
+	changes, err := Diff(dto, model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Change{
+		{"Address.city", "sh", "bj"},
+		{"Age", 18, 20},
+		{"Extra.k", "1", nil},
+		{`Extra["x.y"]`, nil, 2},
+		{"Tags[1]", "b", nil},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("%+v", changes)
+	}
+	if _, err = Diff(DTO{Age: "x"}, model); err == nil {
+		t.Fatal("expected cast error")
+	}
+	if ok, _ := Equal([]int(nil), []int{}); !ok {
+		t.Fatal("nil and empty slice should be equal")
+	}
+}
+
+func TestRecursiveType(t *testing.T) {
+	type A struct {
+		Next *A
+		V    int
+	}
+	type B struct {
+		Next *B
+		V    string
+	}
+	b, err := Cast[*A, *B](&A{&A{nil, 2}, 1})
+	if err != nil || b.V != "1" || b.Next.V != "2" || b.Next.Next != nil {
+		t.Fatal(b, err)
+	}
+	a, err := Cast[B, A](B{&B{nil, "x"}, "1"})
+	if err == nil || a.Next != nil {
+		t.Fatal(a, err)
+	}
+
+	type Tree struct {
+		Name     string
+		Children []Tree
+	}
+	tree, err := Cast[map[string]any, Tree](map[string]any{
+		"name": "root",
+		"children": []any{
+			map[string]any{"name": "a", "children": []any{map[string]any{"name": "a1"}}},
+			map[string]any{"name": "b"},
+		},
+	})
+	if err != nil || !reflect.DeepEqual(tree, Tree{"root", []Tree{{"a", []Tree{{"a1", nil}}}, {"b", nil}}}) {
+		t.Fatal(tree, err)
+	}
+	copied, err := DeepCopy(tree)
+	if err != nil || !reflect.DeepEqual(copied, tree) || &copied.Children[0] == &tree.Children[0] {
+		t.Fatal(copied, err)
+	}
+
+	type C struct {
+		Next *C
+		Ch   chan int
+	}
+	type D struct {
+		Next *D
+		Ch   string
+	}
+	if _, err = Cast[*C, *D](&C{}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// 构建失败时，构建期间缓存的引用了占位的转换器会被移除
+	type E struct {
+		Next []*E
+		Ch   chan int
+	}
+	type F struct {
+		Next []*F
+		Ch   string
+	}
+	s := NewScope()
+	if caster, _ := getCaster(s, typeFor[E](), typeFor[F]()); caster != nil {
+		t.Fatal("expected nil caster")
+	}
+	if caster, _ := getCaster(s, typeFor[*E](), typeFor[*F]()); caster != nil || len(s.building) != 0 {
+		t.Fatal("caster built against a failed placeholder is cached")
+	}
+}
+
+func TestErrorUnsetOrder(t *testing.T) {
+	type Inner struct {
+		B string
+		A string
+	}
+	type From struct {
+		*Inner
+	}
+	type To struct {
+		B string
+		A string
+	}
+	_, err := CastWithScope[From, To](NewScope(WithErrorUnset()), From{})
+	if err == nil || err.Error() != "unset fields <cast.To.A, cast.To.B>" {
+		t.Fatal(err)
+	}
+}
+
+func TestFoldNameConflict(t *testing.T) {
+	// 匿名结构体的字段与外层字段忽略大小写与下划线后同名时，不抢占外层字段的模糊匹配
+	type Inner struct {
+		UserName string
+	}
+	type Outer struct {
+		Inner
+		User_Name string
+	}
+	o, err := Cast[map[string]any, Outer](map[string]any{"username": "a"})
+	if err != nil || o.User_Name != "a" || o.UserName != "" {
+		t.Fatal(o, err)
+	}
+}
+
+func TestUpperCaseFieldFold(t *testing.T) {
+	// 字段名全为大写时，折叠后的名称与字段名相同，也需要忽略大小写匹配
+	type Config struct {
+		DB  string
+		URL string
+	}
+	c, err := Cast[map[string]any, Config](map[string]any{"db": "d", "Url": "u"})
+	if err != nil || c != (Config{"d", "u"}) {
+		t.Fatal(c, err)
+	}
+	type From struct {
+		Db  string
+		Url string
+	}
+	c, err = Cast[From, Config](From{"d", "u"})
+	if err != nil || c != (Config{"d", "u"}) {
+		t.Fatal(c, err)
+	}
+	f, err := Cast[Config, From](Config{"d", "u"})
+	if err != nil || f != (From{"d", "u"}) {
+		t.Fatal(f, err)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 	"unsafe"
 )
@@ -43,20 +44,114 @@ func getCaster(s *Scope, fromType, toType reflect.Type) (castFunc, uint8) {
 	if ok {
 		return v.caster, v.flag
 	}
+	s.mu.Lock()
+	if v, ok = s.casterMap[key]; ok {
+		s.mu.Unlock()
+		return v.caster, v.flag
+	}
+	if p, ok := s.building[key]; ok {
+		// 递归类型构建时会再次遇到自身，先返回占位的转换器，flag 未知，按最保守的处理
+		p.referenced = true
+		s.mu.Unlock()
+		return p.forward, flagHasRef | flagRequireInHeap
+	}
+	p := &pendingCaster{s: s, fromType: fromType, toType: toType, done: make(chan struct{})}
+	s.building[key] = p
+	s.mu.Unlock()
+	built := false
+	defer func() {
+		if !built {
+			// newCaster panic 时移除占位，并唤醒等待的协程
+			s.mu.Lock()
+			s.endBuilding(key, cacheIdx, p, true)
+			s.mu.Unlock()
+			p.resolve(nil)
+		}
+	}()
 	caster, flag := newCaster(s, fromType, toType)
+	built = true
 	v = casterValue{caster, flag}
+	s.mu.Lock()
+	s.endBuilding(key, cacheIdx, p, caster == nil)
+	s.casterMap[key] = v
 	if cacheIdx != -1 {
 		s.casterCache.store(cacheIdx, v)
 	}
-	s.mu.Lock()
-	s.casterMap[key] = v
 	s.mu.Unlock()
+	p.resolve(caster)
 	return caster, flag
 }
 
+// endBuilding 移除构建中的占位，需持有 s.mu。
+// 构建失败且占位被引用过时，构建期间缓存的转换器可能转发给了失败的占位，一并移除，下次获取时重新构建
+func (s *Scope) endBuilding(key casterKey, cacheIdx int, p *pendingCaster, failed bool) {
+	delete(s.building, key)
+	if failed && p.referenced {
+		for _, built := range p.built {
+			delete(s.casterMap, built.key)
+			if built.cacheIdx != -1 {
+				s.casterCache.delete(built.cacheIdx)
+			}
+		}
+	}
+	for _, other := range s.building {
+		other.built = append(other.built, builtCaster{key, cacheIdx})
+	}
+}
+
+// pendingCaster 构建中的转换器的占位，构建完成后转发给真正的转换器。
+// 其他协程在构建期间拿到占位时，调用会等待构建完成
+type pendingCaster struct {
+	s          *Scope
+	fromType   reflect.Type
+	toType     reflect.Type
+	resolved   atomic.Bool
+	done       chan struct{}
+	caster     castFunc
+	referenced bool          // 构建期间是否返回过占位，需持有 s.mu
+	built      []builtCaster // 构建期间缓存的转换器，需持有 s.mu
+}
+
+type builtCaster struct {
+	key      casterKey
+	cacheIdx int
+}
+
+func (p *pendingCaster) resolve(caster castFunc) {
+	p.caster = caster
+	p.resolved.Store(true)
+	close(p.done)
+}
+
+func (p *pendingCaster) forward(fromAddr, toAddr unsafe.Pointer) error {
+	if !p.resolved.Load() {
+		<-p.done
+	}
+	if p.caster == nil {
+		// 递归类型里其他部分无法转换
+		return invalidCastErr(p.s, p.fromType, p.toType)
+	}
+	return p.caster(fromAddr, toAddr)
+}
+
 func newCaster(s *Scope, fromType, toType reflect.Type) (castFunc, uint8) {
-	// 内存布局相同，直接强转
-	if isRefAble(s, fromType, toType) {
+	// 同步原语不拷贝内部状态
+	if fromType == toType && isSyncType(toType) {
+		return getSyncCaster(s, toType)
+	}
+	if s.deepCopy && fromType == toType {
+		if caster, flag, ok := getDeepCopyPolicyCaster(s, toType); ok {
+			return caster, flag
+		}
+		if s.refTracking && isRefTrackable(s, toType) {
+			return getRefTrackingCaster(s, toType)
+		}
+	}
+	// 内存布局相同，直接强转；含有 deep 字段的结构体需逐字段转换
+	if isRefAble(s, fromType, toType) && !hasDeepField(s, fromType) && !hasDeepField(s, toType) {
+		if syncFields := getSyncFields(s, toType); len(syncFields) > 0 {
+			return getSyncFieldsCaster(s, toType, syncFields)
+		}
 		fromTypePtr := typePtr(fromType)
 		return func(fromAddr, toAddr unsafe.Pointer) error {
 			typedMemMove(fromTypePtr, toAddr, fromAddr)
@@ -149,3 +149,53 @@ func getAddressingPointerCaster(s *Scope, fromType, toType reflect.Type) (castFu
 		return elemCaster(fromAddr, toAddr)
 	}, 0
 }
+
+func isSeqType(typ reflect.Type) bool {
+	return typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array
+}
+
+// getFirstElemCaster slice/array 取第一个元素转为非序列类型，用于 url.Values、http.Header 等多值 map 转结构体，空 slice 视为零值
+func getFirstElemCaster(s *Scope, fromType, toType reflect.Type) (castFunc, uint8) {
+	if !isSeqType(fromType) || isSeqType(toType) {
+		return nil, 0
+	}
+	elemCaster, flag := getCaster(s, fromType.Elem(), toType)
+	if elemCaster == nil {
+		return nil, 0
+	}
+	if fromType.Kind() == reflect.Array {
+		if fromType.Len() == 0 {
+			return func(fromAddr, toAddr unsafe.Pointer) error {
+				return nil
+			}, 0
+		}
+		return elemCaster, flag
+	}
+	return func(fromAddr, toAddr unsafe.Pointer) error {
+		from := *(*slice)(fromAddr)
+		if from.len == 0 {
+			return nil
+		}
+		return elemCaster(from.data, toAddr)
+	}, 0
+}
+
+// getWrapSliceCaster 非序列类型转为只有一个元素的 slice，用于结构体转 url.Values、http.Header 等多值 map
+func getWrapSliceCaster(s *Scope, fromType, toType reflect.Type) (castFunc, uint8) {
+	if toType.Kind() != reflect.Slice || isSeqType(fromType) {
+		return nil, 0
+	}
+	toElemType := toType.Elem()
+	elemCaster, flag := getCaster(s, fromType, toElemType)
+	if elemCaster == nil {
+		return nil, 0
+	}
+	return func(fromAddr, toAddr unsafe.Pointer) error {
+		to := makeSlice(toElemType, 1, 1)
+		if err := elemCaster(fromAddr, to.data); err != nil {
+			return err
+		}
+		*(*slice)(toAddr) = to
+		return nil
+	}, flag
+}
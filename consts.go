@@ -11,12 +11,24 @@ import (
 )
 
 var (
-	stringType   = typeFor[string]()
-	stringerType = typeFor[fmt.Stringer]()
-	byteType     = typeFor[byte]()
-	anyType      = typeFor[any]()
-	errType      = typeFor[error]()
-	nilErrValue  = reflect.Zero(errType)
+	stringType    = typeFor[string]()
+	stringerType  = typeFor[fmt.Stringer]()
+	byteType      = typeFor[byte]()
+	anyType       = typeFor[any]()
+	anySliceType  = typeFor[[]any]()
+	anyMapType    = typeFor[map[string]any]()
+	kvType        = typeFor[KV]()
+	errType       = typeFor[error]()
+	defaulterType = typeFor[Defaulter]()
+	isZeroerType  = typeFor[interface{ IsZero() bool }]()
+	nilErrValue   = reflect.Zero(errType)
 )
 
 const zerosSize = 1024
+
+// 结构体字段的拷贝方式
+const (
+	copyDefault uint8 = iota
+	copyShallow       // 深拷贝时也只浅拷贝
+	copyDeep          // 零拷贝时也深拷贝
+)
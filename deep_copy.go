@@ -1,8 +1,16 @@
 package cast
 
+import (
+	"reflect"
+	"unsafe"
+)
+
 var deepCopyScope = NewScope(WithDeepCopy())
 
-// DeepCopy 深拷贝
+var refTrackingScope = NewScope(WithDeepCopy(), WithRefTracking())
+
+// DeepCopy 深拷贝，chan、func、unsafe.Pointer 按默认策略处理（见 DeepCopyDefault），
+// 需要其他策略时，使用开启了 WithDeepCopy 与 WithDeepCopyPolicy 的作用域调用 CastWithScope 或 DeepCopyIntoWithScope
 func DeepCopy[T any](v T) (T, error) {
 	return CastWithScope[T, T](deepCopyScope, v)
 }
@@ -11,3 +19,357 @@ func DeepCopy[T any](v T) (T, error) {
 func GetDeepCopier[T any]() func(T) (T, error) {
 	return GetCasterWithScope[T, T](deepCopyScope)
 }
+
+// DeepCopyWithRefs 类似于 DeepCopy，同时开启 WithRefTracking，保持指针、map 与 slice 底层数组的共享关系，并能拷贝循环引用
+func DeepCopyWithRefs[T any](v T) (T, error) {
+	return CastWithScope[T, T](refTrackingScope, v)
+}
+
+// getDeepCopyPolicyCaster 按作用域里配置的策略获取 chan、func、unsafe.Pointer 的深拷贝转换器，未配置时返回 false
+func getDeepCopyPolicyCaster(s *Scope, typ reflect.Type) (castFunc, uint8, bool) {
+	switch s.deepCopyPolicies[typ.Kind()] {
+	case DeepCopyShare:
+		return func(fromAddr, toAddr unsafe.Pointer) error {
+			*(*unsafe.Pointer)(toAddr) = *(*unsafe.Pointer)(fromAddr)
+			return nil
+		}, 0, true
+	case DeepCopyZero:
+		return func(fromAddr, toAddr unsafe.Pointer) error {
+			return nil
+		}, 0, true
+	case DeepCopyError:
+		// 报错时带上具体的类型，nil 可以直接拷贝
+		return func(fromAddr, toAddr unsafe.Pointer) error {
+			if *(*unsafe.Pointer)(fromAddr) == nil {
+				return nil
+			}
+			return invalidCastErr(s, typ, typ)
+		}, 0, true
+	default:
+		return nil, 0, false
+	}
+}
+
+// isRefTrackable 开启 WithRefTracking 时，类型是否由 refCopier 拷贝，其余类型（包括无可访问字段的结构体）使用普通的深拷贝转换器
+func isRefTrackable(s *Scope, typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface:
+		return true
+	case reflect.Array:
+		return isRefType(typ.Elem())
+	case reflect.Struct:
+		fields := getAllFields(s, typ)
+		return (len(fields.flattened) > 0 || fields.remain != nil) && isRefType(typ) && !isSyncType(typ)
+	default:
+		return false
+	}
+}
+
+// getRefTrackingCaster 同类型深拷贝的转换器，每次转换使用一个新的 refCopier
+func getRefTrackingCaster(s *Scope, typ reflect.Type) (castFunc, uint8) {
+	zeroPtr := getZeroPtr(typ)
+	return func(fromAddr, toAddr unsafe.Pointer) error {
+		c := &refCopier{
+			s:      s,
+			refs:   make(map[refKey]reflect.Value),
+			arrays: make(map[refKey]reflect.Value),
+		}
+		if err := c.copyValue(reflect.NewAt(typ, toAddr).Elem(), reflect.NewAt(typ, fromAddr).Elem()); err != nil {
+			typedMemMove(typePtr(typ), toAddr, zeroPtr)
+			return err
+		}
+		return nil
+	}, flagRequireInHeap
+}
+
+type refKey struct {
+	typ  reflect.Type
+	addr uintptr // 拷贝期间源对象一直存活，用 uintptr 即可
+}
+
+// refCopier 记录已拷贝的指针、map 与 slice 的底层数组，使共享的引用在拷贝后仍然共享，循环引用也能被还原
+type refCopier struct {
+	s      *Scope
+	refs   map[refKey]reflect.Value // 指针与 map 的拷贝
+	arrays map[refKey]reflect.Value // key 为底层数组的末尾地址，value 为拷贝出的从该 slice 起始位置到末尾的底层数组
+}
+
+// copyValue 将 src 深拷贝到 dst，dst 需可寻址且为零值
+func (c *refCopier) copyValue(dst, src reflect.Value) error {
+	typ := src.Type()
+	if !isRefTrackable(c.s, typ) {
+		caster, _ := getCaster(c.s, typ, typ)
+		if caster == nil {
+			return invalidCastErr(c.s, typ, typ)
+		}
+		return caster(getValueAddr(src), dst.Addr().UnsafePointer())
+	}
+	switch typ.Kind() {
+	case reflect.Pointer:
+		if src.IsNil() {
+			return nil
+		}
+		key := refKey{typ, src.Pointer()}
+		if copied, ok := c.refs[key]; ok {
+			dst.Set(copied)
+			return nil
+		}
+		copied := reflect.New(typ.Elem())
+		c.refs[key] = copied
+		dst.Set(copied)
+		return c.copyValue(copied.Elem(), src.Elem())
+	case reflect.Map:
+		if src.IsNil() {
+			return nil
+		}
+		key := refKey{typ, src.Pointer()}
+		if copied, ok := c.refs[key]; ok {
+			dst.Set(copied)
+			return nil
+		}
+		copied := reflect.MakeMapWithSize(typ, src.Len())
+		c.refs[key] = copied
+		dst.Set(copied)
+		iter := src.MapRange()
+		for iter.Next() {
+			k := reflect.New(typ.Key()).Elem()
+			if err := c.copyValue(k, iter.Key()); err != nil {
+				return err
+			}
+			v := reflect.New(typ.Elem()).Elem()
+			if err := c.copyValue(v, iter.Value()); err != nil {
+				return err
+			}
+			copied.SetMapIndex(k, v)
+		}
+		return nil
+	case reflect.Slice:
+		if src.IsNil() {
+			return nil
+		}
+		elemSize := typ.Elem().Size()
+		if elemSize == 0 {
+			dst.Set(reflect.MakeSlice(typ, src.Len(), src.Cap()))
+			return nil
+		}
+		// 共享底层数组的 slice 末尾地址相同，先出现的 slice 起始位置不早于后出现的时才能共享拷贝
+		key := refKey{typ, src.Pointer() + uintptr(src.Cap())*elemSize}
+		if array, ok := c.arrays[key]; ok && array.Cap() >= src.Cap() {
+			start := array.Cap() - src.Cap()
+			dst.Set(array.Slice3(start, start+src.Len(), array.Cap()))
+			return nil
+		}
+		array := reflect.MakeSlice(typ, src.Cap(), src.Cap())
+		c.arrays[key] = array
+		dst.Set(array.Slice(0, src.Len()))
+		full := src.Slice(0, src.Cap())
+		for i := 0; i < full.Len(); i++ {
+			if err := c.copyValue(array.Index(i), full.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Array:
+		for i := 0; i < src.Len(); i++ {
+			if err := c.copyValue(dst.Index(i), src.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Interface:
+		if src.IsNil() {
+			return nil
+		}
+		elem := src.Elem()
+		copied := reflect.New(elem.Type()).Elem()
+		if err := c.copyValue(copied, elem); err != nil {
+			return err
+		}
+		dst.Set(copied)
+		return nil
+	default:
+		// 结构体，字段的规则与 struct 转 struct 一致
+		fields := getAllFields(c.s, typ)
+		srcAddr, dstAddr := getValueAddr(src), dst.Addr().UnsafePointer()
+		for _, field := range append(fields.flattened[:len(fields.flattened):len(fields.flattened)], fields.remain) {
+			if field == nil {
+				continue
+			}
+			srcFieldAddr := field.getAddr(srcAddr, false)
+			if srcFieldAddr == nil {
+				continue
+			}
+			if field.copyMode == copyShallow {
+				typedMemMove(typePtr(field.typ), field.getAddr(dstAddr, true), srcFieldAddr)
+				continue
+			}
+			if err := c.copyValue(reflect.NewAt(field.typ, field.getAddr(dstAddr, true)).Elem(), reflect.NewAt(field.typ, srcFieldAddr).Elem()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// DeepCopyInto 将 src 深拷贝到 *dst 上，并尽量复用 *dst 已有的内存：容量足够的 slice 原地覆盖，map 清空后重新填充，
+// 非 nil 的指针复用其指向的对象，与 src 共享的内存不会被复用。
+// 深拷贝时被忽略的字段（如未导出字段）保持不变，出错时 *dst 可能已被部分修改
+func DeepCopyInto[T any](dst *T, src T) error {
+	return DeepCopyIntoWithScope[T](deepCopyScope, dst, src)
+}
+
+// DeepCopyIntoWithScope 类似于 DeepCopyInto，使用作用域 s 里的转换器与深拷贝策略，s 需开启 WithDeepCopy
+func DeepCopyIntoWithScope[T any](s *Scope, dst *T, src T) error {
+	if dst == nil {
+		return NilPtrErr
+	}
+	return deepCopyInto(s, reflect.ValueOf(dst).Elem(), reflect.ValueOf(&src).Elem())
+}
+
+// deepCopyInto 将 src 深拷贝到 dst，dst 需可寻址，可以不为零值
+func deepCopyInto(s *Scope, dst, src reflect.Value) error {
+	typ := src.Type()
+	if isSyncType(typ) {
+		caster, _ := getSyncCaster(s, typ)
+		dst.Set(reflect.Zero(typ))
+		return caster(getValueAddr(src), dst.Addr().UnsafePointer())
+	}
+	switch typ.Kind() {
+	case reflect.Pointer:
+		if src.IsNil() {
+			dst.Set(reflect.Zero(typ))
+			return nil
+		}
+		if dst.IsNil() || dst.Pointer() == src.Pointer() {
+			dst.Set(reflect.New(typ.Elem()))
+		}
+		return deepCopyInto(s, dst.Elem(), src.Elem())
+	case reflect.Map:
+		if src.IsNil() {
+			dst.Set(reflect.Zero(typ))
+			return nil
+		}
+		if dst.IsNil() || dst.Pointer() == src.Pointer() {
+			dst.Set(reflect.MakeMapWithSize(typ, src.Len()))
+		} else {
+			for _, key := range dst.MapKeys() {
+				dst.SetMapIndex(key, reflect.Value{})
+			}
+		}
+		iter := src.MapRange()
+		for iter.Next() {
+			k := reflect.New(typ.Key()).Elem()
+			if err := deepCopyInto(s, k, iter.Key()); err != nil {
+				return err
+			}
+			v := reflect.New(typ.Elem()).Elem()
+			if err := deepCopyInto(s, v, iter.Value()); err != nil {
+				return err
+			}
+			dst.SetMapIndex(k, v)
+		}
+		return nil
+	case reflect.Slice:
+		if src.IsNil() {
+			dst.Set(reflect.Zero(typ))
+			return nil
+		}
+		oldLen := dst.Len()
+		if dst.IsNil() || dst.Cap() < src.Len() || isOverlapped(dst, src) {
+			dst.Set(reflect.MakeSlice(typ, src.Len(), src.Len()))
+		} else {
+			dst.Set(dst.Slice(0, src.Len()))
+			// 清空不再使用的元素，避免持有旧的引用
+			zero := reflect.Zero(typ.Elem())
+			for i := src.Len(); i < oldLen; i++ {
+				dst.Slice(0, oldLen).Index(i).Set(zero)
+			}
+		}
+		for i := 0; i < src.Len(); i++ {
+			if err := deepCopyInto(s, dst.Index(i), src.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Array:
+		for i := 0; i < src.Len(); i++ {
+			if err := deepCopyInto(s, dst.Index(i), src.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Interface:
+		if src.IsNil() {
+			dst.Set(reflect.Zero(typ))
+			return nil
+		}
+		elem := src.Elem()
+		cur := reflect.New(elem.Type()).Elem()
+		if !dst.IsNil() && dst.Elem().Type() == elem.Type() {
+			cur.Set(dst.Elem())
+		}
+		if err := deepCopyInto(s, cur, elem); err != nil {
+			return err
+		}
+		dst.Set(cur)
+		return nil
+	case reflect.Struct:
+		fields := getAllFields(s, typ)
+		if len(fields.flattened) == 0 && fields.remain == nil {
+			break
+		}
+		srcAddr, dstAddr := getValueAddr(src), dst.Addr().UnsafePointer()
+		for _, field := range fields.flattened {
+			// 展开的匿名结构体指针在 src 里为 nil 或与 src 共享时不复用
+			if parent := field.parent; parent != nil {
+				dstParentAddr, srcParentAddr := parent.getAddr(dstAddr, false), parent.getAddr(srcAddr, false)
+				if dstParentAddr != nil && (srcParentAddr == nil || *(*unsafe.Pointer)(srcParentAddr) == *(*unsafe.Pointer)(dstParentAddr)) {
+					*(*unsafe.Pointer)(dstParentAddr) = nil
+				}
+			}
+		}
+		for _, field := range append(fields.flattened[:len(fields.flattened):len(fields.flattened)], fields.remain) {
+			if field == nil {
+				continue
+			}
+			srcFieldAddr := field.getAddr(srcAddr, false)
+			if srcFieldAddr == nil {
+				continue
+			}
+			dstField := reflect.NewAt(field.typ, field.getAddr(dstAddr, true)).Elem()
+			srcField := reflect.NewAt(field.typ, srcFieldAddr).Elem()
+			if field.copyMode == copyShallow {
+				dstField.Set(srcField)
+				continue
+			}
+			if err := deepCopyInto(s, dstField, srcField); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if !isRefType(typ) && typ.Kind() != reflect.Func {
+		// 未导出字段里的同步原语同样不拷贝内部状态
+		if syncFields := getSyncFields(s, typ); len(syncFields) > 0 {
+			caster, _ := getSyncFieldsCaster(s, typ, syncFields)
+			return caster(getValueAddr(src), dst.Addr().UnsafePointer())
+		}
+		dst.Set(src)
+		return nil
+	}
+	caster, _ := getCaster(s, typ, typ)
+	if caster == nil {
+		return invalidCastErr(s, typ, typ)
+	}
+	dst.Set(reflect.Zero(typ))
+	return caster(getValueAddr(src), dst.Addr().UnsafePointer())
+}
+
+// isOverlapped dst 的底层数组是否与 src 的元素有重叠
+func isOverlapped(dst, src reflect.Value) bool {
+	elemSize := dst.Type().Elem().Size()
+	dstStart, srcStart := dst.Pointer(), src.Pointer()
+	dstEnd := dstStart + uintptr(dst.Cap())*elemSize
+	srcEnd := srcStart + uintptr(src.Len())*elemSize
+	return dstStart < srcEnd && srcStart < dstEnd
+}
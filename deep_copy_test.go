@@ -2,7 +2,11 @@ package cast
 
 import (
 	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"unsafe"
 )
 
 func TestDeepCopy1(t *testing.T) {
@@ -105,3 +109,374 @@ func testMapDeepCopy[K, V comparable](t *testing.T, k K, v V) {
 		t.Fatal()
 	}
 }
+
+func TestDeepCopyRefTracking(t *testing.T) {
+	type Node struct {
+		Val  int
+		Prev *Node
+		Next *Node
+	}
+	type Graph struct {
+		Head   *Node
+		Tail   *Node
+		Index  map[string]*Node
+		Alias  map[string]*Node
+		Buf    []int
+		Window []int
+		Any    any
+	}
+	a := &Node{Val: 1}
+	b := &Node{Val: 2, Prev: a}
+	a.Next = b
+	a.Prev = b
+	index := map[string]*Node{"a": a}
+	buf := []int{1, 2, 3, 4}
+	g := &Graph{Head: a, Tail: b, Index: index, Alias: index, Buf: buf, Window: buf[1:3], Any: a}
+
+	s := NewScope(WithDeepCopy(), WithRefTracking())
+	g2, err := CastWithScope[*Graph, *Graph](s, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g2.Head == a || g2.Head.Next != g2.Tail || g2.Tail.Prev != g2.Head || g2.Head.Prev != g2.Tail {
+		t.Fatal("pointers not shared")
+	}
+	if g2.Index["a"] != g2.Head || g2.Any.(*Node) != g2.Head {
+		t.Fatal("map values and interfaces not shared")
+	}
+	g2.Index["b"] = g2.Tail
+	if g2.Alias["b"] != g2.Tail || len(index) != 1 {
+		t.Fatal("maps not shared")
+	}
+	g2.Buf[1] = 20
+	if g2.Window[0] != 20 || buf[1] != 2 || cap(g2.Window) != 3 {
+		t.Fatal("slices not shared", g2.Window)
+	}
+
+	// 起始位置更靠前的 slice 后出现时无法共享，但内容依然正确
+	type Pair struct {
+		A, B []int
+	}
+	p, err := CastWithScope[Pair, Pair](s, Pair{buf[2:], buf})
+	if err != nil || !reflect.DeepEqual(p, Pair{[]int{3, 4}, []int{1, 2, 3, 4}}) {
+		t.Fatal(p, err)
+	}
+	p.A[0] = 30
+	if p.B[2] != 3 {
+		t.Fatal("slices starting earlier should not be shared", p.B)
+	}
+	// 反过来的顺序可以共享
+	p, err = DeepCopyWithRefs(Pair{buf, buf[2:]})
+	if err != nil || !reflect.DeepEqual(p, Pair{[]int{1, 2, 3, 4}, []int{3, 4}}) {
+		t.Fatal(p, err)
+	}
+	p.B[0] = 30
+	if p.A[2] != 30 || buf[2] != 3 {
+		t.Fatal("slices not shared", p.A)
+	}
+
+	_, err = CastWithScope[[]chan int, []chan int](s, []chan int{nil})
+	if err == nil || err.Error() != "invalid deep copy: can't deep copy type <chan int>" {
+		t.Fatal(err)
+	}
+}
+
+func TestDeepCopyTag(t *testing.T) {
+	type Cache struct {
+		Data map[string]int
+	}
+	type State struct {
+		Cache   *Cache         `cast:",shallow"`
+		Items   []int          `cast:",shallow"`
+		Counter map[string]int `cast:",deep"`
+		Ptr     *int
+	}
+	src := State{&Cache{map[string]int{"a": 1}}, []int{1}, map[string]int{"x": 1}, ptr(1)}
+
+	copied, err := DeepCopy(src)
+	if err != nil || copied.Cache != src.Cache || &copied.Items[0] != &src.Items[0] || copied.Ptr == src.Ptr {
+		t.Fatal(copied, err)
+	}
+	copied.Counter["x"] = 2
+	if src.Counter["x"] != 1 {
+		t.Fatal("deep field shared")
+	}
+
+	// 非深拷贝时，deep 字段依然深拷贝，其余字段浅拷贝
+	casted, err := Cast[State, State](src)
+	if err != nil || casted.Ptr != src.Ptr || casted.Cache != src.Cache {
+		t.Fatal(casted, err)
+	}
+	casted.Counter["x"] = 3
+	if src.Counter["x"] != 1 {
+		t.Fatal("deep field shared")
+	}
+
+	// map 转结构体与开启 WithRefTracking 时同样生效
+	m, err := Cast[map[string]any, State](map[string]any{"Counter": src.Counter, "Cache": src.Cache})
+	if err != nil || m.Cache != src.Cache {
+		t.Fatal(m, err)
+	}
+	m.Counter["x"] = 4
+	if src.Counter["x"] != 1 {
+		t.Fatal("deep field shared")
+	}
+	tracked, err := CastWithScope[State, State](NewScope(WithDeepCopy(), WithRefTracking()), src)
+	if err != nil || tracked.Cache != src.Cache || tracked.Ptr == src.Ptr {
+		t.Fatal(tracked, err)
+	}
+
+	// 同类型的 To 与 slice、指针里的结构体同样生效
+	to, err := To[State](src)
+	if err != nil || to.Cache != src.Cache {
+		t.Fatal(to, err)
+	}
+	to.Counter["x"] = 5
+	if src.Counter["x"] != 1 {
+		t.Fatal("deep field shared")
+	}
+	list, err := Cast[[]State, []State]([]State{src})
+	if err != nil || list[0].Cache != src.Cache {
+		t.Fatal(list, err)
+	}
+	list[0].Counter["x"] = 6
+	if src.Counter["x"] != 1 {
+		t.Fatal("deep field shared")
+	}
+	p, err := Cast[*State, *State](&src)
+	if err != nil || p == &src || p.Cache != src.Cache {
+		t.Fatal(p, err)
+	}
+	p.Counter["x"] = 7
+	if src.Counter["x"] != 1 {
+		t.Fatal("deep field shared")
+	}
+	m2, err := Cast[map[string]State, map[string]State](map[string]State{"a": src})
+	if err != nil || m2["a"].Cache != src.Cache {
+		t.Fatal(m2, err)
+	}
+	m2["a"].Counter["x"] = 8
+	if src.Counter["x"] != 1 {
+		t.Fatal("deep field shared")
+	}
+}
+
+func TestDeepCopyInto(t *testing.T) {
+	type Item struct {
+		ID   int
+		Tags []string
+	}
+	type Request struct {
+		Items  []Item
+		Meta   map[string]string
+		Owner  *Item
+		Shared []int
+		Any    any
+	}
+	dst := Request{
+		Items:  make([]Item, 3, 8),
+		Meta:   map[string]string{"old": "1"},
+		Owner:  &Item{ID: 9},
+		Shared: []int{0, 0},
+		Any:    &Item{},
+	}
+	dst.Items[2].Tags = []string{"stale"}
+	items, meta, owner, anyItem := &dst.Items[0], dst.Meta, dst.Owner, dst.Any.(*Item)
+	shared := []int{1, 2}
+	src := Request{
+		Items:  []Item{{1, []string{"a"}}, {2, nil}},
+		Meta:   map[string]string{"k": "v"},
+		Owner:  &Item{ID: 3},
+		Shared: shared,
+		Any:    &Item{ID: 4},
+	}
+	if err := DeepCopyInto(&dst, src); err != nil {
+		t.Fatal(err)
+	}
+	if &dst.Items[0] != items || dst.Owner != owner || dst.Any.(*Item) != anyItem || reflect.ValueOf(dst.Meta).Pointer() != reflect.ValueOf(meta).Pointer() {
+		t.Fatal("memory not reused")
+	}
+	if !reflect.DeepEqual(dst, src) || dst.Items[:3][2].Tags != nil {
+		t.Fatalf("%+v", dst)
+	}
+
+	// 与 src 共享的内存不会被复用
+	dst.Shared = shared[:0]
+	src.Owner = dst.Owner
+	if err := DeepCopyInto(&dst, src); err != nil {
+		t.Fatal(err)
+	}
+	dst.Shared[0] = 10
+	dst.Owner.ID = 10
+	if shared[0] != 1 || src.Owner.ID != 3 {
+		t.Fatal("memory shared with src")
+	}
+	if err := DeepCopyInto[Request](nil, src); err != NilPtrErr {
+		t.Fatal(err)
+	}
+}
+
+func TestDeepCopyPolicy(t *testing.T) {
+	type Worker struct {
+		Name     string
+		Done     chan struct{}
+		Callback func(int) int
+		Raw      unsafe.Pointer
+	}
+	w := Worker{"w", make(chan struct{}), func(i int) int { return i + 1 }, unsafe.Pointer(new(int))}
+	if _, err := DeepCopy(w); err == nil {
+		t.Fatal("expected error")
+	}
+
+	s := NewScope(WithDeepCopy(), WithDeepCopyPolicy(reflect.Chan, DeepCopyShare), WithDeepCopyPolicy(reflect.Func, DeepCopyShare))
+	copied, err := CastWithScope[Worker, Worker](s, w)
+	if err != nil || copied.Done != w.Done || copied.Callback(1) != 2 || copied.Raw != w.Raw {
+		t.Fatal(copied, err)
+	}
+	if reflect.ValueOf(copied.Callback).Pointer() != reflect.ValueOf(w.Callback).Pointer() {
+		t.Fatal("func not shared")
+	}
+
+	s = NewScope(WithDeepCopy(), WithDeepCopyPolicy(reflect.Chan, DeepCopyZero), WithDeepCopyPolicy(reflect.UnsafePointer, DeepCopyZero))
+	copied, err = CastWithScope[Worker, Worker](s, w)
+	if err != nil || copied.Done != nil || copied.Raw != nil || copied.Callback(1) != 2 {
+		t.Fatal(copied, err)
+	}
+	var into Worker
+	if err = DeepCopyIntoWithScope(s, &into, w); err != nil || into.Done != nil || into.Name != "w" {
+		t.Fatal(into, err)
+	}
+
+	s = NewScope(WithDeepCopy(), WithDeepCopyPolicy(reflect.Func, DeepCopyError), WithDeepCopyPolicy(reflect.Chan, DeepCopyShare))
+	_, err = CastWithScope[Worker, Worker](s, w)
+	if err == nil || err.Error() != "invalid deep copy: can't deep copy type <func(int) int>" {
+		t.Fatal(err)
+	}
+	if copied, err = CastWithScope[Worker, Worker](s, Worker{Name: "w"}); err != nil || copied.Name != "w" {
+		t.Fatal(copied, err)
+	}
+	if s.DeepCopyPolicy(reflect.Chan) != DeepCopyShare || s.DeepCopyPolicy(reflect.Map) != DeepCopyDefault {
+		t.Fatal()
+	}
+	s = NewScope(WithDeepCopy(), WithDeepCopyPolicy(reflect.Map, DeepCopyShare))
+	m := map[string]int{"a": 1}
+	if copiedMap, err := CastWithScope[map[string]int, map[string]int](s, m); err != nil || reflect.ValueOf(copiedMap).Pointer() == reflect.ValueOf(m).Pointer() {
+		t.Fatal("unsupported kind should be ignored", err)
+	}
+}
+
+type noCopy struct{}
+
+func (*noCopy) Lock()   {}
+func (*noCopy) Unlock() {}
+
+func TestCopySyncPrimitives(t *testing.T) {
+	type Counter struct {
+		mu    sync.Mutex
+		_     noCopy
+		Mu    sync.RWMutex
+		WG    sync.WaitGroup
+		Count atomic.Int64
+		Last  atomic.Value
+		data  []int
+		N     int
+	}
+	src := &Counter{data: []int{1}, N: 1}
+	src.mu.Lock()
+	src.Mu.Lock()
+	src.WG.Add(1)
+	src.Count.Store(5)
+	src.Last.Store("x")
+
+	// 非深拷贝时整块拷贝，但同步原语会被置零
+	casted, err := Cast[*Counter, Counter](src)
+	if err != nil || !casted.mu.TryLock() || !casted.Mu.TryLock() || casted.Count.Load() != 0 || casted.Last.Load() != nil || casted.data[0] != 1 || casted.N != 1 {
+		t.Fatal(err)
+	}
+	arr, err := Cast[[2]Counter, [1]Counter]([2]Counter{{N: 2}})
+	if err != nil || arr[0].N != 2 {
+		t.Fatal(err)
+	}
+
+	copied, err := DeepCopy(src)
+	if err != nil || !copied.Mu.TryLock() || copied.Count.Load() != 0 || copied.N != 1 {
+		t.Fatal(err)
+	}
+	copied.WG.Wait()
+
+	s := NewScope(WithDeepCopy(), WithCopyAtomicValues())
+	copied, err = CastWithScope[*Counter, *Counter](s, src)
+	if err != nil || !copied.Mu.TryLock() || copied.Count.Load() != 5 || copied.Last.Load() != "x" {
+		t.Fatal(err)
+	}
+	into := &Counter{}
+	if err = DeepCopyIntoWithScope(s, &into, src); err != nil || !into.Mu.TryLock() || into.Count.Load() != 5 {
+		t.Fatal(err)
+	}
+
+	type View struct {
+		Mu sync.RWMutex
+		N  string
+	}
+	view, err := Cast[*Counter, View](src)
+	if err != nil || !view.Mu.TryLock() || view.N != "1" {
+		t.Fatal(err)
+	}
+
+	// 只有未导出字段的结构体，DeepCopyInto 同样不拷贝其中的锁
+	type Guarded struct {
+		mu sync.Mutex
+		n  int
+	}
+	type Holder struct {
+		G Guarded
+	}
+	holder := &Holder{G: Guarded{n: 3}}
+	holder.G.mu.Lock()
+	dst := &Holder{}
+	if err = DeepCopyInto(&dst, holder); err != nil || !dst.G.mu.TryLock() || dst.G.n != 3 || dst == holder {
+		t.Fatal(err)
+	}
+
+	// 原子类型只通过 Load 读取，与并发的 Store 不构成数据竞争
+	type Stats struct {
+		Head  string
+		Count atomic.Int64
+		Tail  [2]int
+	}
+	stats := &Stats{Head: "h", Tail: [2]int{1, 2}}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := int64(0); i < 1000; i++ {
+			stats.Count.Store(i)
+		}
+	}()
+	as := NewScope(WithCopyAtomicValues())
+	for i := 0; i < 100; i++ {
+		sc, err := CastWithScope[*Stats, Stats](as, stats)
+		if err != nil || sc.Head != "h" || sc.Tail != [2]int{1, 2} {
+			t.Fatal(err)
+		}
+	}
+	<-done
+	statsType := reflect.TypeOf(Stats{})
+	plain := appendPlainFields(as, nil, statsType, 0)
+	if len(plain) != 2 || plain[0].typ != statsType.Field(0).Type || plain[1].offset != statsType.Field(2).Offset {
+		t.Fatal(plain)
+	}
+
+	// sync.Once 拷贝后会重新执行
+	type Lazy struct {
+		Once sync.Once
+		V    int
+	}
+	lazy := &Lazy{V: 1}
+	lazy.Once.Do(func() {})
+	lazyCopy, err := DeepCopy(lazy)
+	ran := false
+	lazyCopy.Once.Do(func() { ran = true })
+	if err != nil || !ran || lazyCopy.V != 1 {
+		t.Fatal(err)
+	}
+}
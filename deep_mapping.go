@@ -0,0 +1,106 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package cast
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// ToMap 将结构体或 map 递归地转为 map[string]any，嵌套的结构体与 map 转为 map[string]any、slice 与 array 转为 []any，
+// 指针转为其指向的值（nil 转为 nil），字段名的规则与 struct 转 map 一致。使用默认作用域的规则，并开启 WithDeepMapping
+func ToMap(v any) (map[string]any, error) {
+	return ToMapWithScope(defaultScope, v)
+}
+
+// ToMapWithScope 类似于 ToMap，使用作用域 s 里的规则，s 未开启 WithDeepMapping 时基于 s 的选项派生开启了的作用域
+func ToMapWithScope(s *Scope, v any) (map[string]any, error) {
+	return ToWithScope[map[string]any](s.forDeepMapping(), v)
+}
+
+// getDeepMappingCaster 获取转为空接口时递归转换的转换器，返回 nil 表示 fromType 为叶子类型，直接装箱即可。
+// 嵌套的转换器在运行时才获取，避免递归类型在构建转换器时无限递归
+func getDeepMappingCaster(s *Scope, fromType, toType reflect.Type) castFunc {
+	var midType reflect.Type
+	switch fromType.Kind() {
+	case reflect.Pointer:
+		elemType := fromType.Elem()
+		return func(fromAddr, toAddr unsafe.Pointer) error {
+			elemAddr := *(*unsafe.Pointer)(fromAddr)
+			if elemAddr == nil {
+				return nil
+			}
+			caster, flag := getCaster(s, elemType, toType)
+			if caster == nil {
+				return invalidCastErr(s, elemType, toType)
+			}
+			if isHasRef(flag) {
+				elemAddr = copyObject(elemType, elemAddr)
+			}
+			return caster(elemAddr, toAddr)
+		}
+	case reflect.Struct:
+		// 无可访问字段的结构体（如 time.Time）视为叶子
+		if fields := getAllFields(s, fromType); len(fields.flattened) == 0 && fields.remain == nil {
+			return nil
+		}
+		midType = anyMapType
+	case reflect.Map:
+		if keyCaster, _ := getCaster(s, fromType.Key(), stringType); keyCaster == nil {
+			return nil
+		}
+		midType = anyMapType
+	case reflect.Slice, reflect.Array:
+		if fromType.Elem().Kind() == reflect.Uint8 {
+			return nil
+		}
+		midType = anySliceType
+	default:
+		return nil
+	}
+	isNilable := isNilableType(fromType)
+	return func(fromAddr, toAddr unsafe.Pointer) error {
+		if isNilable && *(*unsafe.Pointer)(fromAddr) == nil {
+			return nil
+		}
+		caster, flag := getCaster(s, fromType, midType)
+		if caster == nil {
+			return invalidCastErr(s, fromType, midType)
+		}
+		if isHasRef(flag) {
+			fromAddr = copyObject(fromType, fromAddr)
+		}
+		mid := reflect.New(midType)
+		if err := caster(fromAddr, mid.UnsafePointer()); err != nil {
+			return err
+		}
+		*(*any)(toAddr) = mid.Elem().Interface()
+		return nil
+	}
+}
+
+// hasEmptyInterface 判断 typ 里是否含有空接口
+func hasEmptyInterface(typ reflect.Type, visited map[reflect.Type]struct{}) bool {
+	if _, ok := visited[typ]; ok {
+		return false
+	}
+	visited[typ] = struct{}{}
+	switch typ.Kind() {
+	case reflect.Interface:
+		return typ.NumMethod() == 0
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		return hasEmptyInterface(typ.Elem(), visited)
+	case reflect.Map:
+		return hasEmptyInterface(typ.Key(), visited) || hasEmptyInterface(typ.Elem(), visited)
+	case reflect.Struct:
+		for i := 0; i < typ.NumField(); i++ {
+			if hasEmptyInterface(typ.Field(i).Type, visited) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,207 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package cast
+
+import (
+	"reflect"
+	"sort"
+	"unsafe"
+)
+
+// Change 一处差异，Path 的写法与 Get 一致，From 为 a 转换后在该路径上的值，To 为 b 在该路径上的值，不存在时为 nil
+type Change struct {
+	Path string
+	From any
+	To   any
+}
+
+// Equal 将 a 转为 T 后与 b 逐字段比较，字段的对应规则与转换时一致
+func Equal[F any, T any](a F, b T) (bool, error) {
+	return EqualWithScope[F, T](defaultScope, a, b)
+}
+
+// EqualWithScope 类似于 Equal，使用作用域 s 里的转换器
+func EqualWithScope[F any, T any](s *Scope, a F, b T) (bool, error) {
+	changes, err := DiffWithScope[F, T](s, a, b)
+	return len(changes) == 0, err
+}
+
+// Diff 将 a 转为 T 后与 b 逐字段比较，返回按路径排序的差异。
+// 结构体按字段比较（remain 字段里的 key 视为结构体的 key），map 按 key 比较，slice/array 按下标比较，
+// nil 与空的 slice/map 视为相等，无可访问字段的结构体（如 time.Time）优先使用其 Equal 方法比较
+func Diff[F any, T any](a F, b T) ([]Change, error) {
+	return DiffWithScope[F, T](defaultScope, a, b)
+}
+
+// DiffWithScope 类似于 Diff，使用作用域 s 里的转换器
+func DiffWithScope[F any, T any](s *Scope, a F, b T) ([]Change, error) {
+	converted, err := CastWithScope[F, T](s, a)
+	if err != nil {
+		return nil, err
+	}
+	d := &differ{s: s, visited: make(map[visitedPair]struct{})}
+	d.diffValue(reflect.ValueOf(&converted).Elem(), reflect.ValueOf(&b).Elem(), nil)
+	sort.SliceStable(d.changes, func(i, j int) bool { return d.changes[i].Path < d.changes[j].Path })
+	return d.changes, nil
+}
+
+type visitedPair struct {
+	typ  reflect.Type
+	x, y unsafe.Pointer
+}
+
+type differ struct {
+	s       *Scope
+	visited map[visitedPair]struct{} // 比较过的指针对，避免循环引用时无限递归
+	changes []Change
+}
+
+func (d *differ) report(path []pathSegment, x, y reflect.Value) {
+	change := Change{Path: formatPath(path)}
+	if x.IsValid() {
+		change.From = x.Interface()
+	}
+	if y.IsValid() {
+		change.To = y.Interface()
+	}
+	d.changes = append(d.changes, change)
+}
+
+// diffValue 比较 x 与 y，x 或 y 无效时表示该路径上不存在值
+func (d *differ) diffValue(x, y reflect.Value, path []pathSegment) {
+	if !x.IsValid() || !y.IsValid() {
+		if x.IsValid() || y.IsValid() {
+			d.report(path, x, y)
+		}
+		return
+	}
+	if x.Type() != y.Type() {
+		d.report(path, x, y)
+		return
+	}
+	switch x.Kind() {
+	case reflect.Interface:
+		if x.IsNil() || y.IsNil() {
+			if x.IsNil() != y.IsNil() {
+				d.report(path, x, y)
+			}
+			return
+		}
+		d.diffValue(x.Elem(), y.Elem(), path)
+		return
+	case reflect.Pointer:
+		if x.IsNil() || y.IsNil() {
+			if x.IsNil() != y.IsNil() {
+				d.report(path, x, y)
+			}
+			return
+		}
+		pair := visitedPair{x.Type(), x.UnsafePointer(), y.UnsafePointer()}
+		if _, ok := d.visited[pair]; ok || pair.x == pair.y {
+			return
+		}
+		d.visited[pair] = struct{}{}
+		d.diffValue(x.Elem(), y.Elem(), path)
+		return
+	case reflect.Struct:
+		fields := getAllFields(d.s, x.Type())
+		if len(fields.flattened) == 0 && fields.remain == nil {
+			break
+		}
+		d.diffStruct(x, y, fields, path)
+		return
+	case reflect.Map:
+		d.diffMap(x, y, path)
+		return
+	case reflect.Slice, reflect.Array:
+		length := x.Len()
+		if y.Len() > length {
+			length = y.Len()
+		}
+		for i := 0; i < length; i++ {
+			var xi, yi reflect.Value
+			if i < x.Len() {
+				xi = x.Index(i)
+			}
+			if i < y.Len() {
+				yi = y.Index(i)
+			}
+			d.diffValue(xi, yi, appendPath(path, pathSegment{isIndex: true, index: i}))
+		}
+		return
+	}
+	if !leafEqual(x, y) {
+		d.report(path, x, y)
+	}
+}
+
+// diffStruct 按字段比较，展开的嵌入指针为 nil 时其字段视为不存在
+func (d *differ) diffStruct(x, y reflect.Value, fields structFields, path []pathSegment) {
+	xAddr, yAddr := getValueAddr(x), getValueAddr(y)
+	for _, field := range fields.flattened {
+		fieldPath := appendPath(path, pathSegment{key: field.name})
+		if field.keyPath != nil {
+			fieldPath = path[:len(path):len(path)]
+			for _, key := range field.keyPath {
+				fieldPath = append(fieldPath, pathSegment{key: key})
+			}
+		}
+		d.diffValue(fieldValue(field, xAddr), fieldValue(field, yAddr), fieldPath)
+	}
+	if fields.remain != nil {
+		d.diffMap(fieldValue(fields.remain, xAddr), fieldValue(fields.remain, yAddr), path)
+	}
+}
+
+// diffMap 按 key 比较，x 或 y 可以是无效值
+func (d *differ) diffMap(x, y reflect.Value, path []pathSegment) {
+	var keys []reflect.Value
+	seen := make(map[any]struct{})
+	for _, m := range [2]reflect.Value{x, y} {
+		if !m.IsValid() {
+			continue
+		}
+		for _, key := range m.MapKeys() {
+			if _, ok := seen[key.Interface()]; !ok {
+				seen[key.Interface()] = struct{}{}
+				keys = append(keys, key)
+			}
+		}
+	}
+	for _, key := range keys {
+		var xv, yv reflect.Value
+		if x.IsValid() {
+			xv = x.MapIndex(key)
+		}
+		if y.IsValid() {
+			yv = y.MapIndex(key)
+		}
+		d.diffValue(xv, yv, appendPath(path, pathSegment{key: flattenMapKey(d.s, key)}))
+	}
+}
+
+func fieldValue(field *structField, addr unsafe.Pointer) reflect.Value {
+	fieldAddr := field.getAddr(addr, false)
+	if fieldAddr == nil {
+		return reflect.Value{}
+	}
+	return reflect.NewAt(field.typ, fieldAddr).Elem()
+}
+
+// appendPath 追加路径段，不修改 path 的底层数组
+func appendPath(path []pathSegment, seg pathSegment) []pathSegment {
+	return append(path[:len(path):len(path)], seg)
+}
+
+// leafEqual 比较叶子节点，存在 Equal(T) bool 方法时优先使用
+func leafEqual(x, y reflect.Value) bool {
+	if method, ok := x.Type().MethodByName("Equal"); ok {
+		if mt := method.Type; mt.NumIn() == 2 && mt.In(1) == x.Type() && mt.NumOut() == 1 && mt.Out(0).Kind() == reflect.Bool {
+			return method.Func.Call([]reflect.Value{x, y})[0].Bool()
+		}
+	}
+	return reflect.DeepEqual(x.Interface(), y.Interface())
+}
@@ -0,0 +1,222 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package cast
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"unicode"
+	"unsafe"
+)
+
+// FromEnv 将环境变量绑定到结构体 T 上，如 prefix 为 APP 时，APP_DB_HOST 对应 T.DB.Host。
+// 环境变量名为前缀与各层字段名以 _ 连接后的结果，忽略大小写匹配，字段名的规则与 map 转 struct 一致；
+// 驼峰式的字段名也可以按单词以 _ 分隔（如 MaxConns 对应 MAX_CONNS），与其他字段冲突时不生效。
+// slice/array 类型的字段会按 , 分割后再转换，错误信息里会带上环境变量名
+func FromEnv[T any](prefix string) (T, error) {
+	return FromEnvWithScope[T](defaultScope, prefix, os.Environ())
+}
+
+// FromEnvWithScope 类似于 FromEnv，使用作用域 s 里的转换器，environ 的格式与 os.Environ() 一致
+func FromEnvWithScope[T any](s *Scope, prefix string, environ []string) (T, error) {
+	var to T
+	toType := typeFor[T]()
+	if toType.Kind() != reflect.Struct {
+		return to, invalidCastErr(s, stringType, toType)
+	}
+	upperPrefix := strings.ToUpper(strings.TrimSuffix(prefix, "_"))
+	if upperPrefix != "" {
+		upperPrefix += "_"
+	}
+	vars := make(map[string]envVar, len(environ))
+	for _, kv := range environ {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok {
+			continue
+		}
+		upperName := strings.ToUpper(name)
+		if !strings.HasPrefix(upperName, upperPrefix) {
+			continue
+		}
+		// 去掉前缀后按字段路径匹配，同名时以后出现的为准
+		vars[upperName[len(upperPrefix):]] = envVar{name, value}
+	}
+	if err := bindEnv(s, toType, unsafe.Pointer(&to), []string{""}, vars, make(map[reflect.Type]struct{})); err != nil {
+		var zero T
+		return zero, err
+	}
+	return to, nil
+}
+
+type envVar struct {
+	name  string
+	value string
+}
+
+// bindEnv 将 vars 绑定到 addr 指向的结构体上，paths 为字段路径前缀的候选写法（大写，以 _ 结尾或为空），优先级从高到低
+func bindEnv(s *Scope, typ reflect.Type, addr unsafe.Pointer, paths []string, vars map[string]envVar, visited map[reflect.Type]struct{}) error {
+	visited[typ] = struct{}{}
+	defer delete(visited, typ)
+	if setDefaults := getDefaultsSetter(typ); setDefaults != nil {
+		setDefaults(addr)
+	}
+	fields := getAllFields(s, typ).flattened
+	names := getEnvNames(fields)
+	for i, field := range fields {
+		keys := make([]string, 0, len(paths)*len(names[i]))
+		for _, path := range paths {
+			for _, name := range names[i] {
+				keys = append(keys, path+name)
+			}
+		}
+		// 没有 string 转换器的结构体（指针）字段，继续匹配其字段
+		if elemType, isPtr, ok := getEnvStructType(s, field.typ); ok {
+			if _, ok := visited[elemType]; ok {
+				continue
+			}
+			for j := range keys {
+				keys[j] += "_"
+			}
+			if isPtr && !hasEnvPrefix(vars, keys) {
+				// 没有对应环境变量的结构体指针保持为 nil
+				continue
+			}
+			fieldAddr := field.getAddr(addr, true)
+			if isPtr {
+				if *(*unsafe.Pointer)(fieldAddr) == nil {
+					*(*unsafe.Pointer)(fieldAddr) = newObject(elemType)
+				}
+				fieldAddr = *(*unsafe.Pointer)(fieldAddr)
+			}
+			if err := bindEnv(s, elemType, fieldAddr, keys, vars, visited); err != nil {
+				return err
+			}
+			continue
+		}
+		if env, ok := lookupEnv(vars, keys); ok {
+			if err := castEnv(s, env.value, field.typ, field.getAddr(addr, true)); err != nil {
+				return fmt.Errorf("env <%s>: %w", env.name, err)
+			}
+			continue
+		}
+		if field.isRequired {
+			return requiredFieldNotMatchErr(typ, field.rawName)
+		}
+		defaultCaster, ok := getDefaultCaster(s, field)
+		if !ok {
+			return invalidCastErr(s, stringType, field.typ)
+		}
+		if err := setDefault(field, defaultCaster, addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getEnvNames 获取各字段在环境变量名里的写法：大写的字段名（. 与 - 替换为 _），以及按单词以 _ 分隔的写法。
+// 后者与其他字段的写法相同，或者与其他字段的写法互为以 _ 分隔的前缀时不生效，如 DBHost 的 DB_HOST 与 DB.Host 冲突
+func getEnvNames(fields []*structField) [][]string {
+	replacer := strings.NewReplacer(".", "_", "-", "_")
+	exact := make([]string, len(fields))
+	for i, field := range fields {
+		exact[i] = strings.ToUpper(replacer.Replace(field.name))
+	}
+	names := make([][]string, len(fields))
+	for i, field := range fields {
+		names[i] = []string{exact[i]}
+		alias := splitWords(replacer.Replace(field.name))
+		if alias == exact[i] {
+			continue
+		}
+		conflict := false
+		for j := range fields {
+			if j != i && (envNameConflict(alias, exact[j]) || envNameConflict(alias, splitWords(replacer.Replace(fields[j].name)))) {
+				conflict = true
+				break
+			}
+		}
+		if !conflict {
+			names[i] = append(names[i], alias)
+		}
+	}
+	return names
+}
+
+// envNameConflict a 与 b 相同，或者互为以 _ 分隔的前缀
+func envNameConflict(a, b string) bool {
+	return a == b || strings.HasPrefix(a, b+"_") || strings.HasPrefix(b, a+"_")
+}
+
+// splitWords 将驼峰式的名称按单词以 _ 分隔并转为大写，如 MaxConns 转为 MAX_CONNS、DBHost 转为 DB_HOST
+func splitWords(name string) string {
+	var sb strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) && runes[i-1] != '_' {
+			prev := runes[i-1]
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				sb.WriteByte('_')
+			}
+		}
+		sb.WriteRune(unicode.ToUpper(r))
+	}
+	return sb.String()
+}
+
+// getEnvStructType 判断字段是否为需要继续展开的结构体（指针）
+func getEnvStructType(s *Scope, typ reflect.Type) (reflect.Type, bool, bool) {
+	if caster, _ := getCaster(s, stringType, typ); caster != nil {
+		return nil, false, false
+	}
+	if typ.Kind() == reflect.Struct {
+		return typ, false, true
+	}
+	if typ.Kind() == reflect.Pointer && typ.Elem().Kind() == reflect.Struct {
+		return typ.Elem(), true, true
+	}
+	return nil, false, false
+}
+
+// lookupEnv 按优先级查找 keys 对应的环境变量
+func lookupEnv(vars map[string]envVar, keys []string) (envVar, bool) {
+	for _, key := range keys {
+		if env, ok := vars[key]; ok {
+			return env, true
+		}
+	}
+	return envVar{}, false
+}
+
+// hasEnvPrefix 是否存在以 prefixes 之一开头的环境变量，prefixes 均以 _ 结尾
+func hasEnvPrefix(vars map[string]envVar, prefixes []string) bool {
+	for key := range vars {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// castEnv 将环境变量的值转为 toType，slice/array（[]byte 与 []rune 除外）按 , 分割
+func castEnv(s *Scope, value string, toType reflect.Type, toAddr unsafe.Pointer) error {
+	if isSeqType(toType) {
+		if elemKind := toType.Elem().Kind(); elemKind != reflect.Uint8 && elemKind != reflect.Int32 {
+			var parts []string
+			if value != "" {
+				parts = strings.Split(value, ",")
+				for i := range parts {
+					parts[i] = strings.TrimSpace(parts[i])
+				}
+			}
+			return castValue(s, reflect.ValueOf(parts), toType, toAddr)
+		}
+	}
+	return castValue(s, reflect.ValueOf(value), toType, toAddr)
+}
@@ -7,6 +7,8 @@ package cast
 
 import (
 	"reflect"
+	"sort"
+	"strings"
 )
 
 type strErr string
@@ -36,3 +38,118 @@ func getTypeString(typ reflect.Type) string {
 func requiredFieldNotMatchErr(toType reflect.Type, fieldName string) error {
 	return strErr("required field <" + toType.String() + "." + fieldName + "> not match")
 }
+
+func unusedKeysErr(fromType, toType reflect.Type, keys []string) error {
+	return strErr("unused keys <" + strings.Join(keys, ", ") + "> when casting <" + fromType.String() + "> to <" + toType.String() + ">")
+}
+
+func unusedFieldsErr(fromType reflect.Type, fieldNames []string) error {
+	return strErr("unused fields <" + joinFieldNames(fromType, fieldNames) + ">")
+}
+
+func unsetFieldsErr(toType reflect.Type, fieldNames []string) error {
+	return strErr("unset fields <" + joinFieldNames(toType, fieldNames) + ">")
+}
+
+// strictErr WithErrorUnused、WithErrorUnset 的错误，嵌套结构体的错误会带上字段路径合并到外层，最终一起报告
+type strictErr struct {
+	fromType reflect.Type
+	toType   reflect.Type
+	unused   []string // 未被使用的源 key/下标（源为 map、slice、array 时）或源字段的路径
+	unset    []string // 未被赋值的目标字段的路径
+}
+
+func (e *strictErr) Error() string {
+	var parts []string
+	if len(e.unused) > 0 {
+		if kind := e.fromType.Kind(); kind == reflect.Map || kind == reflect.Slice || kind == reflect.Array {
+			parts = append(parts, unusedKeysErr(e.fromType, e.toType, e.unused).Error())
+		} else {
+			parts = append(parts, unusedFieldsErr(e.fromType, e.unused).Error())
+		}
+	}
+	if len(e.unset) > 0 {
+		parts = append(parts, unsetFieldsErr(e.toType, e.unset).Error())
+	}
+	return strings.Join(parts, "; ")
+}
+
+// strictCollector 转为结构体时收集未被使用的 key/字段与未被赋值的字段
+type strictCollector struct {
+	unused []string
+	unset  []string
+}
+
+// merge 合并嵌套字段的 strictErr，unusedPrefix、unsetPrefix 分别为该字段在源与目标里的路径，err 不是 strictErr 时返回 false
+func (c *strictCollector) merge(err error, unusedPrefix, unsetPrefix string) bool {
+	se, ok := err.(*strictErr)
+	if !ok {
+		return false
+	}
+	for _, name := range se.unused {
+		c.unused = append(c.unused, joinPath(unusedPrefix, name))
+	}
+	for _, name := range se.unset {
+		c.unset = append(c.unset, joinPath(unsetPrefix, name))
+	}
+	return true
+}
+
+// err 没有收集到任何问题时返回 nil
+func (c *strictCollector) err(fromType, toType reflect.Type) error {
+	if len(c.unused) == 0 && len(c.unset) == 0 {
+		return nil
+	}
+	sort.Strings(c.unused)
+	sort.Strings(c.unset)
+	return &strictErr{fromType, toType, c.unused, c.unset}
+}
+
+// joinPath 拼接路径，下标（如 [1]）前不加 .
+func joinPath(prefix, name string) string {
+	if strings.HasPrefix(name, "[") {
+		return prefix + name
+	}
+	return prefix + "." + name
+}
+
+func joinFieldNames(typ reflect.Type, fieldNames []string) string {
+	var sb strings.Builder
+	for i, name := range fieldNames {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(typ.String())
+		sb.WriteByte('.')
+		sb.WriteString(name)
+	}
+	return sb.String()
+}
+
+func invalidIndexErr(typ reflect.Type, fieldName string) error {
+	return strErr("invalid index of field <" + typ.String() + "." + fieldName + ">")
+}
+
+func duplicateIndexErr(typ reflect.Type, fieldName string) error {
+	return strErr("duplicate index of field <" + typ.String() + "." + fieldName + ">")
+}
+
+func invalidPathErr(path string) error {
+	return strErr("invalid path <" + path + ">")
+}
+
+func pathNotFoundErr(path, prefix string) error {
+	return strErr("<" + prefix + "> not found when getting path <" + path + ">")
+}
+
+func pathNotSettableErr(path, prefix string) error {
+	return strErr("<" + prefix + "> not settable when setting path <" + path + ">")
+}
+
+func invalidSetTargetErr(target reflect.Value) error {
+	var typ reflect.Type
+	if target.IsValid() {
+		typ = target.Type()
+	}
+	return strErr("set target must be a non-nil pointer, got <" + getTypeString(typ) + ">")
+}
@@ -0,0 +1,165 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package cast
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Flatten 将嵌套的结构体、map、slice、array 展开为以 sep 连接路径的单层 map，slice/array 的下标作为路径的一段，
+// 如 {"db": {"hosts": ["a"]}} 展开为 {"db.hosts.0": "a"}。sep 为空时使用 "."
+func Flatten(v any, sep string) map[string]any {
+	return FlattenWithScope(defaultScope, v, sep)
+}
+
+// FlattenWithScope 类似于 Flatten，结构体的字段名按作用域 s 的规则获取
+func FlattenWithScope(s *Scope, v any, sep string) map[string]any {
+	if sep == "" {
+		sep = "."
+	}
+	out := make(map[string]any)
+	flattenValue(s, reflect.ValueOf(v), "", sep, out)
+	return out
+}
+
+func joinFlattenKey(prefix, sep, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + sep + key
+}
+
+// flattenValue 展开 v 并写入 out，nil、空容器与无字段的结构体（如 time.Time）作为叶子节点
+func flattenValue(s *Scope, v reflect.Value, prefix, sep string, out map[string]any) {
+	v = indirectValue(v)
+	if !v.IsValid() {
+		out[prefix] = nil
+		return
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		fields := getAllFields(s, v.Type())
+		if len(fields.flattened) == 0 && fields.remain == nil {
+			break
+		}
+		addr := getValueAddr(v)
+		for _, field := range fields.flattened {
+			name := field.name
+			if field.keyPath != nil {
+				name = strings.Join(field.keyPath, sep)
+			}
+			key := joinFlattenKey(prefix, sep, name)
+			fieldAddr := field.getAddr(addr, false)
+			if fieldAddr == nil {
+				out[key] = nil
+				continue
+			}
+			flattenValue(s, reflect.NewAt(field.typ, fieldAddr).Elem(), key, sep, out)
+		}
+		if fields.remain != nil {
+			if remainAddr := fields.remain.getAddr(addr, false); remainAddr != nil {
+				remain := reflect.NewAt(fields.remain.typ, remainAddr).Elem()
+				iter := remain.MapRange()
+				for iter.Next() {
+					key := joinFlattenKey(prefix, sep, iter.Key().String())
+					if _, ok := out[key]; !ok {
+						flattenValue(s, iter.Value(), key, sep, out)
+					}
+				}
+			}
+		}
+		return
+	case reflect.Map:
+		if v.Len() == 0 {
+			break
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			flattenValue(s, iter.Value(), joinFlattenKey(prefix, sep, flattenMapKey(s, iter.Key())), sep, out)
+		}
+		return
+	case reflect.Slice, reflect.Array:
+		if v.Len() == 0 || v.Type().Elem() == byteType {
+			break
+		}
+		for i := 0; i < v.Len(); i++ {
+			flattenValue(s, v.Index(i), joinFlattenKey(prefix, sep, strconv.Itoa(i)), sep, out)
+		}
+		return
+	}
+	out[prefix] = v.Interface()
+}
+
+// flattenMapKey 将 map 的 key 转为 string，无法转换时使用 fmt.Sprint
+func flattenMapKey(s *Scope, key reflect.Value) string {
+	if key.Kind() == reflect.String {
+		return key.String()
+	}
+	if k, err := ReflectCastWithScope(s, key, stringType); err == nil {
+		return k.String()
+	}
+	return fmt.Sprint(key.Interface())
+}
+
+// Unflatten 是 Flatten 的逆操作，将以 sep 连接路径的单层 map 还原为嵌套的 map[string]any，
+// key 恰好为 0~n-1 的中间层会还原为 []any。路径冲突时（如同时存在 a 与 a.b），更长的路径优先。sep 为空时使用 "."
+func Unflatten[V any](m map[string]V, sep string) map[string]any {
+	if sep == "" {
+		sep = "."
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	// 排序使得结果与 map 的遍历顺序无关，前缀总是排在更长的路径之前
+	sort.Strings(keys)
+	out := make(map[string]any, len(m))
+	for _, k := range keys {
+		path := strings.Split(k, sep)
+		cur := out
+		for _, seg := range path[:len(path)-1] {
+			next, ok := cur[seg].(map[string]any)
+			if !ok {
+				next = make(map[string]any)
+				cur[seg] = next
+			}
+			cur = next
+		}
+		last := path[len(path)-1]
+		if _, ok := cur[last].(map[string]any); ok {
+			continue
+		}
+		cur[last] = m[k]
+	}
+	for k, v := range out {
+		out[k] = restoreSlices(v)
+	}
+	return out
+}
+
+// restoreSlices 递归地把 key 恰好为 0~n-1 的 map[string]any 转为 []any
+func restoreSlices(v any) any {
+	m, ok := v.(map[string]any)
+	if !ok || len(m) == 0 {
+		return v
+	}
+	for k, elem := range m {
+		m[k] = restoreSlices(elem)
+	}
+	list := make([]any, len(m))
+	for k, elem := range m {
+		i, err := strconv.Atoi(k)
+		if err != nil || i < 0 || i >= len(m) || strconv.Itoa(i) != k {
+			return m
+		}
+		list[i] = elem
+	}
+	return list
+}
@@ -14,7 +14,7 @@ func getInterfaceCaster(s *Scope, fromType, toType reflect.Type) (castFunc, uint
 	fromKind := fromType.Kind()
 	if toType.NumMethod() == 0 {
 		if fromKind == reflect.Interface {
-			if s.deepCopy {
+			if s.deepCopy || s.deepMapping {
 				return getUnpackInterfaceCaster(s, fromType, toType)
 			}
 			if fromType.NumMethod() == 0 {
@@ -29,6 +29,11 @@ func getInterfaceCaster(s *Scope, fromType, toType reflect.Type) (castFunc, uint
 				}, 0
 			}
 		}
+		if s.deepMapping {
+			if caster := getDeepMappingCaster(s, fromType, toType); caster != nil {
+				return caster, 0
+			}
+		}
 		if s.deepCopy {
 			copier, _ := getCaster(s, fromType, fromType)
 			if copier == nil {
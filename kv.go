@@ -0,0 +1,116 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package cast
+
+import (
+	"reflect"
+	"sort"
+	"unsafe"
+)
+
+// KV 有序的键值对，结构体转为 []KV 时按字段的声明顺序排列，展开的匿名/内联字段排在结构体自身的字段之后，
+// []KV 也可以转为结构体或 map，key 重复时后面的覆盖前面的
+type KV struct {
+	Key   string
+	Value any
+}
+
+// getStructToKVsCaster 结构体转 []KV，字段名与忽略规则和 struct 转 map 一致，remain 字段里的 key 按字典序追加到末尾
+func getStructToKVsCaster(s *Scope, fromType reflect.Type) (castFunc, uint8) {
+	type metaField struct {
+		structField
+		caster castFunc
+		omit   func(addr unsafe.Pointer) bool
+	}
+	fields := getAllFields(s, fromType)
+	var flag uint8
+	metaFields := make([]metaField, 0, len(fields.flattened))
+	for _, field := range fields.flattened {
+		caster, fFlag := getCaster(s, field.typ, anyType)
+		if caster == nil {
+			return nil, 0
+		}
+		metaFields = append(metaFields, metaField{
+			structField: *field,
+			caster:      caster,
+			omit:        getOmitChecker(field.typ, field.omitEmpty || s.omitEmpty, field.omitZero || s.omitZero),
+		})
+		flag |= fFlag
+	}
+	remain := fields.remain
+	var remainCaster castFunc
+	var remainElemType reflect.Type
+	if remain != nil {
+		remainElemType = remain.typ.Elem()
+		var rFlag uint8
+		remainCaster, rFlag = getCaster(s, remainElemType, anyType)
+		if remainCaster == nil {
+			return nil, 0
+		}
+		flag |= rFlag
+	}
+	return func(fromAddr, toAddr unsafe.Pointer) error {
+		kvs := make([]KV, 0, len(metaFields))
+		for i := range metaFields {
+			field := &metaFields[i]
+			fromFieldAddr := field.getAddr(fromAddr, false)
+			if fromFieldAddr == nil || field.omit != nil && field.omit(fromFieldAddr) {
+				continue
+			}
+			kv := KV{Key: field.name}
+			if err := field.caster(fromFieldAddr, noEscape(unsafe.Pointer(&kv.Value))); err != nil {
+				return err
+			}
+			kvs = append(kvs, kv)
+		}
+		if remain != nil {
+			if remainAddr := remain.getAddr(fromAddr, false); remainAddr != nil {
+				m := reflect.NewAt(remain.typ, remainAddr).Elem()
+				keys := make([]string, 0, m.Len())
+				for _, k := range m.MapKeys() {
+					keys = append(keys, k.String())
+				}
+				sort.Strings(keys)
+				existed := make(map[string]struct{}, len(kvs))
+				for _, kv := range kvs {
+					existed[kv.Key] = struct{}{}
+				}
+				for _, key := range keys {
+					if _, ok := existed[key]; ok {
+						continue
+					}
+					value := m.MapIndex(reflect.ValueOf(key).Convert(remain.typ.Key()))
+					kv := KV{Key: key}
+					if err := remainCaster(getValueAddr(value), noEscape(unsafe.Pointer(&kv.Value))); err != nil {
+						return err
+					}
+					kvs = append(kvs, kv)
+				}
+			}
+		}
+		*(*[]KV)(toAddr) = kvs
+		return nil
+	}, flag
+}
+
+// getKVsCaster []KV 转为结构体或 map，先转为 map[string]any 再复用其转换器
+func getKVsCaster(s *Scope, toType reflect.Type) (castFunc, uint8) {
+	caster, _ := getCaster(s, anyMapType, toType)
+	if caster == nil {
+		return nil, 0
+	}
+	return func(fromAddr, toAddr unsafe.Pointer) error {
+		kvs := *(*[]KV)(fromAddr)
+		if kvs == nil {
+			return nil
+		}
+		m := make(map[string]any, len(kvs))
+		for _, kv := range kvs {
+			m[kv.Key] = kv.Value
+		}
+		return caster(unsafe.Pointer(&m), toAddr)
+	}, 0
+}
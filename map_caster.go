@@ -6,7 +6,9 @@
 package cast
 
 import (
+	"net/textproto"
 	"reflect"
+	"strings"
 	"sync/atomic"
 	"unsafe"
 )
@@ -81,6 +83,11 @@ func getMapCaster(s *Scope, fromType, toType reflect.Type) (castFunc, uint8) {
 		}, 0
 	case reflect.Pointer:
 		return getAddressingPointerCaster(s, fromType, toType)
+	case reflect.Slice:
+		if fromType.Elem() == kvType {
+			return getKVsCaster(s, toType)
+		}
+		return nil, 0
 	case reflect.Struct:
 		toKeyType := toType.Key()
 		keyCaster, _ := getCaster(s, stringType, toKeyType)
@@ -92,10 +99,23 @@ func getMapCaster(s *Scope, fromType, toType reflect.Type) (castFunc, uint8) {
 			structField
 			key    unsafe.Pointer
 			caster castFunc
+			omit   func(addr unsafe.Pointer) bool
 		}
 		fields := getAllFields(s, fromType)
 		toMapHelper := newMapHelper(toType)
-		if len(fields.flattened) == 0 {
+		// remain 字段里的 key 会合并到结果里，但不会覆盖同名字段
+		remain := fields.remain
+		var remainCaster castFunc
+		var remainFlag uint8
+		var remainMapHelper *mapHelper
+		if remain != nil {
+			remainCaster, remainFlag = getCaster(s, remain.typ.Elem(), toElemType)
+			if remainCaster == nil {
+				return nil, 0
+			}
+			remainMapHelper = newMapHelper(remain.typ)
+		}
+		if len(fields.flattened) == 0 && remain == nil {
 			return func(fromAddr, toAddr unsafe.Pointer) error {
 				*(*map[any]any)(toAddr) = toMapHelper.Make(0)
 				return nil
@@ -104,14 +124,24 @@ func getMapCaster(s *Scope, fromType, toType reflect.Type) (castFunc, uint8) {
 		var flag uint8
 		keyIsStr := toKeyType.Kind() == reflect.String
 		keyIsRefType := isRefType(toKeyType)
+		// 值为空接口时，cast tag 为路径的字段会写入嵌套的 map[string]any，否则以整个路径为 key
+		nestable := keyIsStr && toElemType.Kind() == reflect.Interface && toElemType.NumMethod() == 0
+		isHeader := isHTTPHeader(toType)
 		metaFields := make([]metaField, 0, len(fields.flattened))
 		for _, field := range fields.flattened {
-			caster, fFlag := getCaster(s, field.typ, toElemType)
+			caster, fFlag := getCaster(s.forCopyMode(field.copyMode), field.typ, toElemType)
+			if caster == nil {
+				// 转为多值 map（如 url.Values）时，非序列字段转为只有一个元素的 slice
+				caster, fFlag = getWrapSliceCaster(s, field.typ, toElemType)
+			}
 			if caster == nil {
 				return nil, 0
 			}
 			var fieldKey unsafe.Pointer
-			if keyIsStr {
+			if isHeader {
+				name := headerKey(field.name)
+				fieldKey = unsafe.Pointer(&name)
+			} else if keyIsStr {
 				fieldKey = unsafe.Pointer(&field.name)
 			} else if !keyIsRefType {
 				fieldKey = newObject(toKeyType)
@@ -123,6 +153,7 @@ func getMapCaster(s *Scope, fromType, toType reflect.Type) (castFunc, uint8) {
 				structField: *field,
 				key:         fieldKey,
 				caster:      caster,
+				omit:        getOmitChecker(field.typ, field.omitEmpty || s.omitEmpty, field.omitZero || s.omitZero),
 			})
 			flag |= fFlag
 		}
@@ -134,6 +165,7 @@ func getMapCaster(s *Scope, fromType, toType reflect.Type) (castFunc, uint8) {
 			to := toMapHelper.Make(len(fields.flattened))
 			*(*map[any]any)(toAddr) = to
 			var v unsafe.Pointer
+			var owned ownedMaps
 			if mu.CompareAndSwap(0, 1) {
 				defer mu.Store(0)
 				v = globalValueBuffer
@@ -155,7 +187,7 @@ func getMapCaster(s *Scope, fromType, toType reflect.Type) (castFunc, uint8) {
 					k = field.key
 				}
 				fromFieldAddr := field.getAddr(fromAddr, false)
-				if fromFieldAddr == nil {
+				if fromFieldAddr == nil || field.omit != nil && field.omit(fromFieldAddr) {
 					continue
 				}
 				typedMemMove(typePtr(toElemType), v, valueZeroPtr)
@@ -163,11 +195,107 @@ func getMapCaster(s *Scope, fromType, toType reflect.Type) (castFunc, uint8) {
 					*(*map[any]any)(toAddr) = nil
 					return err
 				}
+				if nestable && field.keyPath != nil {
+					storeNested(toMapHelper, to, field.keyPath, *(*any)(v), &owned)
+					continue
+				}
+				toMapHelper.Store(to, k, v)
+			}
+			if remain == nil {
+				return nil
+			}
+			remainAddr := remain.getAddr(fromAddr, false)
+			if remainAddr == nil {
+				return nil
+			}
+			var err error
+			remainMapHelper.Range(*(*map[any]any)(remainAddr), func(key, value unsafe.Pointer) bool {
+				k := key
+				if isHeader {
+					name := textproto.CanonicalMIMEHeaderKey(*(*string)(key))
+					k = unsafe.Pointer(&name)
+				} else if !keyIsStr {
+					k = newObject(toKeyType)
+					name := *(*string)(key)
+					if err = keyCaster(unsafe.Pointer(&name), k); err != nil {
+						return false
+					}
+				}
+				if _, ok := toMapHelper.Load(to, k); ok {
+					return true
+				}
+				if isHasRef(remainFlag) {
+					value = copyObject(remain.typ.Elem(), value)
+				}
+				typedMemMove(typePtr(toElemType), v, valueZeroPtr)
+				if err = remainCaster(value, v); err != nil {
+					return false
+				}
 				toMapHelper.Store(to, k, v)
+				return true
+			})
+			if err != nil {
+				*(*map[any]any)(toAddr) = nil
 			}
-			return nil
+			return err
 		}, flag
 	default:
 		return nil, 0
 	}
 }
+
+// isHTTPHeader 判断是否为 http.Header，避免为此引入 net/http
+func isHTTPHeader(typ reflect.Type) bool {
+	return typ.Name() == "Header" && typ.PkgPath() == "net/http"
+}
+
+// headerKey 把字段名转为规范的 header key：不含 - 的名称先按驼峰拆分单词，如 ContentType、content_type 均转为 Content-Type
+func headerKey(name string) string {
+	if !strings.Contains(name, "-") {
+		name = strings.ReplaceAll(splitWords(name), "_", "-")
+	}
+	return textproto.CanonicalMIMEHeaderKey(name)
+}
+
+// ownedMaps 本次转换新建的中间层 map，其余的中间层可能与源值共享，写入前需要先拷贝
+type ownedMaps map[unsafe.Pointer]struct{}
+
+// own 返回可以写入的中间层：m 不是本次新建的时拷贝一份，并返回 true
+func (o *ownedMaps) own(m map[string]any) (map[string]any, bool) {
+	if m != nil {
+		if _, ok := (*o)[*(*unsafe.Pointer)(unsafe.Pointer(&m))]; ok {
+			return m, false
+		}
+	}
+	owned := make(map[string]any, len(m))
+	for k, v := range m {
+		owned[k] = v
+	}
+	if *o == nil {
+		*o = make(ownedMaps)
+	}
+	(*o)[*(*unsafe.Pointer)(unsafe.Pointer(&owned))] = struct{}{}
+	return owned, true
+}
+
+// storeNested 按路径把 value 写入嵌套的 map[string]any，中间层不存在或不是 map[string]any 时新建，已存在的中间层拷贝后再写入
+func storeNested(toMapHelper *mapHelper, to map[any]any, path []string, value any, owned *ownedMaps) {
+	first := path[0]
+	var m map[string]any
+	if p, ok := toMapHelper.Load(to, unsafe.Pointer(&first)); ok {
+		m, _ = (*(*any)(p)).(map[string]any)
+	}
+	m, copied := owned.own(m)
+	if copied {
+		var elem any = m
+		toMapHelper.Store(to, unsafe.Pointer(&first), unsafe.Pointer(&elem))
+	}
+	for _, key := range path[1 : len(path)-1] {
+		next, _ := m[key].(map[string]any)
+		if next, copied = owned.own(next); copied {
+			m[key] = next
+		}
+		m = next
+	}
+	m[path[len(path)-1]] = value
+}
@@ -0,0 +1,274 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package cast
+
+import (
+	"fmt"
+	"reflect"
+	"unsafe"
+)
+
+// Merge 按顺序将 sources（map、结构体或它们的指针）合并到同一个 T 上，后面的源优先级更高。
+// 源里缺失的值（nil、map 里不存在的 key、结构体的零值字段）不会覆盖已有的值；嵌套的结构体与 map 会递归合并而不是整体替换；
+// slice 默认整体替换，可通过 WithMergeAppendSlice 改为追加。合并不会修改 sources 里的 map 与 slice。
+// 字段按名称整体匹配 key，tag 里的 default、required 与以 . 分隔的路径均不生效
+func Merge[T any](sources ...any) (T, error) {
+	return MergeWithScope[T](defaultScope, sources...)
+}
+
+// MergeWithScope 类似于 Merge，使用作用域 s 里的转换器与合并策略
+func MergeWithScope[T any](s *Scope, sources ...any) (T, error) {
+	var to T
+	v := reflect.NewAt(typeFor[T](), noEscape(unsafe.Pointer(&to))).Elem()
+	mg := &merger{s: s, skipZero: true}
+	for i, source := range sources {
+		if err := mg.mergeValue(v, reflect.ValueOf(source)); err != nil {
+			var zero T
+			return zero, fmt.Errorf("merge source <%d>: %w", i, err)
+		}
+	}
+	return to, nil
+}
+
+// CastInto 将 from 应用到已有的 *to 上（PATCH 语义）：map 源里不存在的 key 与结构体源里为 nil 的指针/map/slice 字段不会修改目标，
+// 其他值（包括零值）会覆盖目标；嵌套的结构体、指针指向的值与 map 原地更新（nil map 会新建）。出错时 *to 可能已被部分更新。
+// 与 Merge 一样，字段按名称整体匹配 key，tag 里的 default、required 与以 . 分隔的路径均不生效
+func CastInto[F any, T any](s *Scope, from F, to *T) error {
+	if to == nil {
+		return NilPtrErr
+	}
+	mg := &merger{s: s, inPlace: true}
+	return mg.mergeValue(reflect.ValueOf(to).Elem(), reflect.ValueOf(&from).Elem())
+}
+
+// merger 合并引擎，Merge 与 CastInto 共用
+type merger struct {
+	s        *Scope
+	skipZero bool // 源结构体的零值字段是否视为缺失，为 false 时只有 nil 视为缺失
+	inPlace  bool // 是否直接修改目标里已有的 map，为 false 时先拷贝，避免修改之前的源
+}
+
+// mergeValue 将 src 合并到 dst 上，dst 需可寻址
+func (mg *merger) mergeValue(dst, src reflect.Value) error {
+	s := mg.s
+	src = indirectValue(src)
+	if !src.IsValid() || isNilContainer(src) {
+		return nil
+	}
+	switch dst.Kind() {
+	case reflect.Pointer:
+		if src.Kind() != reflect.Struct && src.Kind() != reflect.Map {
+			break
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return mg.mergeValue(dst.Elem(), src)
+	case reflect.Interface:
+		if dst.IsNil() {
+			break
+		}
+		elem := dst.Elem()
+		if !isMergeable(elem) || !isMergeable(src) {
+			break
+		}
+		cur := reflect.New(elem.Type()).Elem()
+		cur.Set(elem)
+		if err := mg.mergeValue(cur, src); err != nil {
+			return err
+		}
+		dst.Set(cur)
+		return nil
+	case reflect.Struct:
+		// 无可访问字段的结构体（如 time.Time）整体替换
+		if fields := getAllFields(s, dst.Type()); len(fields.flattened) == 0 && fields.remain == nil {
+			break
+		}
+		switch src.Kind() {
+		case reflect.Map:
+			return mg.mergeMapIntoStruct(dst, src)
+		case reflect.Struct:
+			if fields := getAllFields(s, src.Type()); len(fields.flattened) > 0 || fields.remain != nil {
+				return mg.mergeStructIntoStruct(dst, src)
+			}
+		}
+	case reflect.Map:
+		if src.Kind() != reflect.Map && src.Kind() != reflect.Struct {
+			break
+		}
+		return mg.mergeIntoMap(dst, src)
+	case reflect.Slice:
+		if !s.mergeAppendSlice || dst.Len() == 0 || !isSeqType(src.Type()) {
+			break
+		}
+		converted, err := ReflectCastWithScope(s, src, dst.Type())
+		if err != nil {
+			return err
+		}
+		merged := reflect.MakeSlice(dst.Type(), dst.Len()+converted.Len(), dst.Len()+converted.Len())
+		reflect.Copy(merged, dst)
+		reflect.Copy(merged.Slice(dst.Len(), merged.Len()), converted)
+		dst.Set(merged)
+		return nil
+	}
+	converted, err := ReflectCastWithScope(s, src, dst.Type())
+	if err != nil {
+		return err
+	}
+	dst.Set(converted)
+	return nil
+}
+
+func isNilContainer(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Chan, reflect.Func:
+		return v.IsNil()
+	default:
+		return false
+	}
+}
+
+func isMergeable(v reflect.Value) bool {
+	v = indirectValue(v)
+	return v.IsValid() && (v.Kind() == reflect.Map || v.Kind() == reflect.Struct)
+}
+
+// mergeMapIntoStruct 按 key 合并到结构体字段上，未匹配的 key 合并到 remain 字段里
+func (mg *merger) mergeMapIntoStruct(dst, src reflect.Value) error {
+	s := mg.s
+	fields := getAllFields(s, dst.Type())
+	addr := dst.Addr().UnsafePointer()
+	var remain reflect.Value
+	iter := src.MapRange()
+	for iter.Next() {
+		key, err := ReflectCastWithScope(s, iter.Key(), stringType)
+		if err != nil {
+			continue
+		}
+		name := key.String()
+		field, ok := fields.byActualName[name]
+		if !ok {
+			field, ok = fields.byFoldedName[foldNameStr(name)]
+		}
+		if !ok {
+			if fields.remain == nil {
+				continue
+			}
+			if !remain.IsValid() {
+				remain = reflect.NewAt(fields.remain.typ, fields.remain.getAddr(addr, true)).Elem()
+				if !mg.inPlace || remain.IsNil() {
+					remain.Set(copyMap(remain))
+				}
+			}
+			if err = mg.mergeMapEntry(remain, name, iter.Value()); err != nil {
+				return err
+			}
+			continue
+		}
+		if err = mg.mergeValue(reflect.NewAt(field.typ, field.getAddr(addr, true)).Elem(), iter.Value()); err != nil {
+			return fmt.Errorf("field <%s>: %w", field.rawName, err)
+		}
+	}
+	return nil
+}
+
+// mergeStructIntoStruct 按字段名合并，skipZero 时源结构体的零值字段视为缺失
+func (mg *merger) mergeStructIntoStruct(dst, src reflect.Value) error {
+	s := mg.s
+	dstFields := getAllFields(s, dst.Type())
+	srcFields := getAllFields(s, src.Type())
+	dstAddr := dst.Addr().UnsafePointer()
+	srcAddr := getValueAddr(src)
+	for _, srcField := range srcFields.flattened {
+		field, ok := dstFields.byActualName[srcField.name]
+		if !ok && srcField.foldedName != "" {
+			field, ok = dstFields.byFoldedName[srcField.foldedName]
+		}
+		if !ok {
+			continue
+		}
+		srcFieldAddr := srcField.getAddr(srcAddr, false)
+		if srcFieldAddr == nil {
+			continue
+		}
+		v := reflect.NewAt(srcField.typ, srcFieldAddr).Elem()
+		if mg.skipZero && v.IsZero() {
+			continue
+		}
+		if err := mg.mergeValue(reflect.NewAt(field.typ, field.getAddr(dstAddr, true)).Elem(), v); err != nil {
+			return fmt.Errorf("field <%s>: %w", field.rawName, err)
+		}
+	}
+	if srcFields.remain != nil && dstFields.remain != nil {
+		if remainAddr := srcFields.remain.getAddr(srcAddr, false); remainAddr != nil {
+			return mg.mergeValue(reflect.NewAt(dstFields.remain.typ, dstFields.remain.getAddr(dstAddr, true)).Elem(),
+				reflect.NewAt(srcFields.remain.typ, remainAddr).Elem())
+		}
+	}
+	return nil
+}
+
+// mergeIntoMap 将 map 或结构体合并到 map 上，非 inPlace 时会先拷贝一份 dst，避免修改之前的源
+func (mg *merger) mergeIntoMap(dst, src reflect.Value) error {
+	s := mg.s
+	if src.Kind() == reflect.Struct {
+		// 结构体先转为 map[string]any，零值字段按 skipZero 的规则处理
+		m := make(map[string]any)
+		srcFields := getAllFields(s, src.Type())
+		srcAddr := getValueAddr(src)
+		for _, field := range srcFields.flattened {
+			if fieldAddr := field.getAddr(srcAddr, false); fieldAddr != nil {
+				if v := reflect.NewAt(field.typ, fieldAddr).Elem(); !mg.skipZero || !v.IsZero() {
+					m[field.name] = v.Interface()
+				}
+			}
+		}
+		src = reflect.ValueOf(m)
+	}
+	if !mg.inPlace || dst.IsNil() {
+		dst.Set(copyMap(dst))
+	}
+	iter := src.MapRange()
+	for iter.Next() {
+		key, err := ReflectCastWithScope(s, iter.Key(), dst.Type().Key())
+		if err != nil {
+			return err
+		}
+		cur := reflect.New(dst.Type().Elem()).Elem()
+		if existing := dst.MapIndex(key); existing.IsValid() {
+			cur.Set(existing)
+		}
+		if err = mg.mergeValue(cur, iter.Value()); err != nil {
+			return fmt.Errorf("key <%v>: %w", key.Interface(), err)
+		}
+		dst.SetMapIndex(key, cur)
+	}
+	return nil
+}
+
+// mergeMapEntry 将 src 合并到 m[key] 上，非 inPlace 时 m 需已经拷贝过
+func (mg *merger) mergeMapEntry(m reflect.Value, key string, src reflect.Value) error {
+	k := reflect.ValueOf(key).Convert(m.Type().Key())
+	cur := reflect.New(m.Type().Elem()).Elem()
+	if existing := m.MapIndex(k); existing.IsValid() {
+		cur.Set(existing)
+	}
+	if err := mg.mergeValue(cur, src); err != nil {
+		return fmt.Errorf("key <%s>: %w", key, err)
+	}
+	m.SetMapIndex(k, cur)
+	return nil
+}
+
+// copyMap 浅拷贝 map，nil 时返回新建的空 map
+func copyMap(m reflect.Value) reflect.Value {
+	copied := reflect.MakeMapWithSize(m.Type(), m.Len())
+	iter := m.MapRange()
+	for iter.Next() {
+		copied.SetMapIndex(iter.Key(), iter.Value())
+	}
+	return copied
+}
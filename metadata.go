@@ -0,0 +1,99 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package cast
+
+import (
+	"sort"
+	"strings"
+)
+
+// Metadata 转为结构体时的字段匹配情况，路径为以 . 分隔的 key/字段名
+type Metadata struct {
+	Keys   []string // 被使用了的源 key/字段
+	Unused []string // 未被使用的源 key/字段
+	Unset  []string // 未被赋值的目标字段，使用默认值的字段视为已赋值
+	Folded []string // 忽略大小写与下划线后才匹配上的源 key/字段
+}
+
+type metadataCollector struct {
+	md   *Metadata
+	path []string // 当前正在转换的字段路径
+}
+
+func (c *metadataCollector) fullPath(name string) string {
+	if len(c.path) == 0 {
+		return name
+	}
+	return strings.Join(c.path, ".") + "." + name
+}
+
+func (c *metadataCollector) addKey(name string, folded bool) {
+	path := c.fullPath(name)
+	c.md.Keys = append(c.md.Keys, path)
+	if folded {
+		c.md.Folded = append(c.md.Folded, path)
+	}
+}
+
+func (c *metadataCollector) addUnused(name string) {
+	c.md.Unused = append(c.md.Unused, c.fullPath(name))
+}
+
+func (c *metadataCollector) addUnset(name string) {
+	c.md.Unset = append(c.md.Unset, c.fullPath(name))
+}
+
+func (c *metadataCollector) push(name string) {
+	c.path = append(c.path, name)
+}
+
+func (c *metadataCollector) pop() {
+	c.path = c.path[:len(c.path)-1]
+}
+
+func withCollectMetadata() ScopeOption {
+	return func(s *Scope) {
+		if s.frozen {
+			return
+		}
+		s.collectMetadata = true
+	}
+}
+
+// forMetadata 获取记录字段匹配情况的作用域，总是基于 s 的选项派生，派生作用域上的调用会再派生一层，因此不会与外层调用争用记录器
+func (s *Scope) forMetadata() *Scope {
+	s.metadataOnce.Do(func() {
+		s.metadataScope = s.derive(withCollectMetadata())
+	})
+	return s.metadataScope
+}
+
+// getMetadata 获取本次转换的记录器，未开启记录时返回 nil
+func (s *Scope) getMetadata() *metadataCollector {
+	for s.metadataOwner != nil {
+		s = s.metadataOwner
+	}
+	return s.metadata
+}
+
+// CastWithMetadata 类似于 CastWithScope，额外返回 map/struct 转为结构体时的字段匹配情况（包括嵌套的字段），各列表按字典序排列。
+// 记录字段匹配情况的转换器构建在基于 s 的选项派生的作用域里并被复用；记录器是每次调用独有的，同一作用域上的并发调用会串行执行
+func CastWithMetadata[F any, T any](s *Scope, from F) (T, *Metadata, error) {
+	ms := s.forMetadata()
+	md := &Metadata{}
+	to, err := func() (T, error) {
+		ms.metadataMu.Lock()
+		defer ms.metadataMu.Unlock()
+		ms.metadata = &metadataCollector{md: md}
+		defer func() { ms.metadata = nil }()
+		return CastWithScope[F, T](ms, from)
+	}()
+	sort.Strings(md.Keys)
+	sort.Strings(md.Unused)
+	sort.Strings(md.Unset)
+	sort.Strings(md.Folded)
+	return to, md, err
+}
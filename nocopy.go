@@ -0,0 +1,143 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package cast
+
+import (
+	"reflect"
+	"sync"
+	"unsafe"
+)
+
+var lockerType = typeFor[sync.Locker]()
+
+// isSyncType 是否为不能拷贝的同步原语：sync 与 sync/atomic 包里的结构体（如 sync.Mutex、sync.WaitGroup、atomic.Int64），
+// 以及无字段且指针实现了 sync.Locker 的 noCopy 标记类型。
+// 这些类型均拷贝为零值：sync.Once 拷贝后会重新执行，sync.Map 与 sync.Pool 拷贝后为空
+func isSyncType(typ reflect.Type) bool {
+	if typ.Kind() != reflect.Struct {
+		return false
+	}
+	if pkgPath := typ.PkgPath(); pkgPath == "sync" || pkgPath == "sync/atomic" {
+		return true
+	}
+	return typ.NumField() == 0 && reflect.PointerTo(typ).Implements(lockerType)
+}
+
+// syncField 结构体（包括按值嵌套的结构体与数组）里同步原语的位置
+type syncField struct {
+	offset  uintptr
+	typ     reflect.Type
+	zeroPtr unsafe.Pointer
+	caster  castFunc
+}
+
+// getSyncFields 获取 typ 里按值存放的同步原语，包括未导出字段
+func getSyncFields(s *Scope, typ reflect.Type) []syncField {
+	return appendSyncFields(s, nil, typ, 0)
+}
+
+func appendSyncFields(s *Scope, fields []syncField, typ reflect.Type, offset uintptr) []syncField {
+	switch typ.Kind() {
+	case reflect.Struct:
+		if isSyncType(typ) {
+			caster, _ := getSyncCaster(s, typ)
+			return append(fields, syncField{offset, typ, getZeroPtr(typ), caster})
+		}
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			fields = appendSyncFields(s, fields, field.Type, offset+field.Offset)
+		}
+	case reflect.Array:
+		elemType := typ.Elem()
+		if elemFields := appendSyncFields(s, nil, elemType, 0); len(elemFields) > 0 {
+			for i := 0; i < typ.Len(); i++ {
+				for _, field := range elemFields {
+					field.offset += offset + uintptr(i)*elemType.Size()
+					fields = append(fields, field)
+				}
+			}
+		}
+	}
+	return fields
+}
+
+func hasSyncField(s *Scope, typ reflect.Type) bool {
+	return len(getSyncFields(s, typ)) > 0
+}
+
+// plainField 结构体里同步原语之外的部分，不含同步原语的结构体与数组作为一个整体
+type plainField struct {
+	offset uintptr
+	typ    reflect.Type
+}
+
+// appendPlainFields 获取 typ 里除同步原语外的部分，拷贝时只读取这些部分，不会以非原子的方式读取同步原语
+func appendPlainFields(s *Scope, fields []plainField, typ reflect.Type, offset uintptr) []plainField {
+	if isSyncType(typ) {
+		return fields
+	}
+	if !hasSyncField(s, typ) {
+		return append(fields, plainField{offset, typ})
+	}
+	switch typ.Kind() {
+	case reflect.Struct:
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			fields = appendPlainFields(s, fields, field.Type, offset+field.Offset)
+		}
+	case reflect.Array:
+		elemType := typ.Elem()
+		for i := 0; i < typ.Len(); i++ {
+			fields = appendPlainFields(s, fields, elemType, offset+uintptr(i)*elemType.Size())
+		}
+	}
+	return fields
+}
+
+// getSyncCaster 同步原语的转换器，默认拷贝为零值（toAddr 已经是零值）；
+// 开启 WithCopyAtomicValues 时，sync/atomic 里的类型通过 Load、Store 方法原子地拷贝值
+func getSyncCaster(s *Scope, typ reflect.Type) (castFunc, uint8) {
+	if s.copyAtomicValues && typ.PkgPath() == "sync/atomic" {
+		ptrType := reflect.PointerTo(typ)
+		load, ok1 := ptrType.MethodByName("Load")
+		store, ok2 := ptrType.MethodByName("Store")
+		if ok1 && ok2 {
+			return func(fromAddr, toAddr unsafe.Pointer) error {
+				v := load.Func.Call([]reflect.Value{reflect.NewAt(typ, fromAddr)})[0]
+				if v.Kind() == reflect.Interface && v.IsNil() {
+					// atomic.Value 未存储过值
+					return nil
+				}
+				store.Func.Call([]reflect.Value{reflect.NewAt(typ, toAddr), v})
+				return nil
+			}, 0
+		}
+	}
+	return func(fromAddr, toAddr unsafe.Pointer) error {
+		return nil
+	}, 0
+}
+
+// getSyncFieldsCaster 逐个拷贝同步原语之外的部分，再按同步原语的转换器写入其中的同步原语，
+// 使得同步原语只会通过其转换器（如 atomic 类型的 Load）读取
+func getSyncFieldsCaster(s *Scope, typ reflect.Type, fields []syncField) (castFunc, uint8) {
+	plainFields := appendPlainFields(s, nil, typ, 0)
+	return func(fromAddr, toAddr unsafe.Pointer) error {
+		for i := range plainFields {
+			field := &plainFields[i]
+			typedMemMove(typePtr(field.typ), unsafe.Add(toAddr, field.offset), unsafe.Add(fromAddr, field.offset))
+		}
+		for i := range fields {
+			field := &fields[i]
+			fieldAddr := unsafe.Add(toAddr, field.offset)
+			typedMemMove(typePtr(field.typ), fieldAddr, field.zeroPtr)
+			if err := field.caster(unsafe.Add(fromAddr, field.offset), fieldAddr); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, 0
+}
@@ -0,0 +1,371 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package cast
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// Get 按路径从 data 中取值并转为 T，路径支持 a.b、a[0]、a["b.c"]、a."b.c" 等写法，
+// 可以访问 map、slice、array、指针、interface 与结构体（字段名的匹配规则与转换时一致）
+func Get[T any](data any, path string) (T, error) {
+	return GetWithScope[T](defaultScope, data, path)
+}
+
+// GetWithScope 类似于 Get，使用作用域 s 里的转换器
+func GetWithScope[T any](s *Scope, data any, path string) (T, error) {
+	var to T
+	segments, err := parsePath(path)
+	if err != nil {
+		return to, err
+	}
+	v := reflect.ValueOf(data)
+	for i, seg := range segments {
+		var ok bool
+		if seg.isIndex {
+			v, ok = lookupIndex(s, v, seg.index)
+		} else {
+			v, ok = lookupKey(s, v, seg.key)
+		}
+		if !ok {
+			return to, pathNotFoundErr(path, formatPath(segments[:i+1]))
+		}
+	}
+	err = castValue(s, v, typeFor[T](), noEscape(unsafe.Pointer(&to)))
+	return to, err
+}
+
+// Set 按路径把 value 写入 target 中，target 需为非 nil 指针，路径写法与 Get 一致。
+// 路径上缺失的 map、slice、指针会自动创建，slice 长度不足时会扩容，interface 为 nil 时按下一段路径创建 map[string]any 或 []any，
+// value 会转为目标位置的类型
+func Set(target any, path string, value any) error {
+	return SetWithScope(defaultScope, target, path, value)
+}
+
+// SetWithScope 类似于 Set，使用作用域 s 里的转换器
+func SetWithScope(s *Scope, target any, path string, value any) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return invalidSetTargetErr(v)
+	}
+	segments, err := parsePath(path)
+	if err != nil {
+		return err
+	}
+	return setPath(s, v.Elem(), segments, 0, path, value)
+}
+
+// setPath 将 value 写入 v 的 segments[i:] 路径下，v 需可寻址
+func setPath(s *Scope, v reflect.Value, segments []pathSegment, i int, path string, value any) error {
+	if i == len(segments) {
+		converted, err := ReflectCastWithScope(s, reflect.ValueOf(value), v.Type())
+		if err != nil {
+			return err
+		}
+		v.Set(converted)
+		return nil
+	}
+	seg := segments[i]
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setPath(s, v.Elem(), segments, i, path, value)
+	case reflect.Interface:
+		var cur reflect.Value
+		if !v.IsNil() {
+			elem := v.Elem()
+			cur = reflect.New(elem.Type()).Elem()
+			cur.Set(elem)
+		} else if v.NumMethod() == 0 {
+			if seg.isIndex {
+				cur = reflect.New(anySliceType).Elem()
+			} else {
+				cur = reflect.New(anyMapType).Elem()
+			}
+		} else {
+			return pathNotSettableErr(path, formatPath(segments[:i+1]))
+		}
+		if err := setPath(s, cur, segments, i, path, value); err != nil {
+			return err
+		}
+		v.Set(cur)
+		return nil
+	case reflect.Map:
+		keyType := v.Type().Key()
+		key := seg.key
+		if seg.isIndex {
+			key = strconv.Itoa(seg.index)
+		}
+		var k reflect.Value
+		if keyType.Kind() == reflect.String {
+			k = reflect.ValueOf(key).Convert(keyType)
+		} else {
+			var err error
+			if k, err = ReflectCastWithScope(s, reflect.ValueOf(key), keyType); err != nil {
+				return err
+			}
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		cur := reflect.New(v.Type().Elem()).Elem()
+		if elem := v.MapIndex(k); elem.IsValid() {
+			cur.Set(elem)
+		}
+		if err := setPath(s, cur, segments, i+1, path, value); err != nil {
+			return err
+		}
+		v.SetMapIndex(k, cur)
+		return nil
+	case reflect.Slice, reflect.Array:
+		index, ok := seg.index, seg.isIndex
+		if !ok {
+			// 兼容 a.0.b 的写法
+			n, err := strconv.Atoi(seg.key)
+			index, ok = n, err == nil && n >= 0
+		}
+		if !ok {
+			return pathNotSettableErr(path, formatPath(segments[:i+1]))
+		}
+		if index >= v.Len() {
+			if v.Kind() == reflect.Array {
+				return pathNotSettableErr(path, formatPath(segments[:i+1]))
+			}
+			grown := reflect.MakeSlice(v.Type(), index+1, index+1)
+			reflect.Copy(grown, v)
+			v.Set(grown)
+		}
+		return setPath(s, v.Index(index), segments, i+1, path, value)
+	case reflect.Struct:
+		if seg.isIndex {
+			return pathNotSettableErr(path, formatPath(segments[:i+1]))
+		}
+		fields := getAllFields(s, v.Type())
+		field, ok := fields.byActualName[seg.key]
+		if !ok {
+			if field, ok = fields.byFoldedName[foldNameStr(seg.key)]; !ok {
+				return pathNotSettableErr(path, formatPath(segments[:i+1]))
+			}
+		}
+		addr := field.getAddr(v.Addr().UnsafePointer(), true)
+		return setPath(s, reflect.NewAt(field.typ, addr).Elem(), segments, i+1, path, value)
+	default:
+		return pathNotSettableErr(path, formatPath(segments[:i+1]))
+	}
+}
+
+type pathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// parsePath 解析路径，. 分隔 key，[n] 为下标，["key"] 与 "key" 为带引号的 key（支持 strconv.Unquote 的转义）
+func parsePath(path string) ([]pathSegment, error) {
+	var segments []pathSegment
+	for i := 0; i < len(path); {
+		switch c := path[i]; {
+		case c == '.' && i > 0 && i+1 < len(path) && path[i+1] != '.' && path[i+1] != '[':
+			i++
+			continue
+		case c == '[':
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, invalidPathErr(path)
+			}
+			inner := path[i+1 : i+end]
+			if len(inner) > 0 && (inner[0] == '"' || inner[0] == '\'') {
+				key, n, err := unquotePrefix(path[i+1:])
+				if err != nil || i+1+n >= len(path) || path[i+1+n] != ']' {
+					return nil, invalidPathErr(path)
+				}
+				segments = append(segments, pathSegment{key: key})
+				i += n + 2
+			} else {
+				index, err := strconv.Atoi(inner)
+				if err != nil || index < 0 {
+					return nil, invalidPathErr(path)
+				}
+				segments = append(segments, pathSegment{index: index, isIndex: true})
+				i += end + 1
+			}
+		case c == '"' || c == '\'':
+			key, n, err := unquotePrefix(path[i:])
+			if err != nil {
+				return nil, invalidPathErr(path)
+			}
+			segments = append(segments, pathSegment{key: key})
+			i += n
+		case c == '.':
+			return nil, invalidPathErr(path)
+		default:
+			end := strings.IndexAny(path[i:], ".[")
+			if end < 0 {
+				end = len(path) - i
+			}
+			segments = append(segments, pathSegment{key: path[i : i+end]})
+			i += end
+		}
+		// 每个段之后只能是 .、[ 或结束
+		if i < len(path) && path[i] != '.' && path[i] != '[' {
+			return nil, invalidPathErr(path)
+		}
+	}
+	return segments, nil
+}
+
+// unquotePrefix 解析 s 开头的带引号字符串，返回内容与引号部分的长度
+func unquotePrefix(s string) (string, int, error) {
+	quoted, err := strconv.QuotedPrefix(s)
+	if err != nil {
+		return "", 0, err
+	}
+	key, err := strconv.Unquote(quoted)
+	return key, len(quoted), err
+}
+
+// formatPath 将路径段重新格式化，用于错误信息
+func formatPath(segments []pathSegment) string {
+	var sb strings.Builder
+	for i, seg := range segments {
+		switch {
+		case seg.isIndex:
+			sb.WriteByte('[')
+			sb.WriteString(strconv.Itoa(seg.index))
+			sb.WriteByte(']')
+		case seg.key == "" || strings.ContainsAny(seg.key, ".[]\"'"):
+			sb.WriteByte('[')
+			sb.WriteString(strconv.Quote(seg.key))
+			sb.WriteByte(']')
+		default:
+			if i > 0 {
+				sb.WriteByte('.')
+			}
+			sb.WriteString(seg.key)
+		}
+	}
+	return sb.String()
+}
+
+// lookupIndex 按下标查找，map 会把下标转为 key 查找
+func lookupIndex(s *Scope, v reflect.Value, index int) (reflect.Value, bool) {
+	v = indirectValue(v)
+	if !v.IsValid() {
+		return reflect.Value{}, false
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array, reflect.String:
+		if index >= v.Len() {
+			return reflect.Value{}, false
+		}
+		return v.Index(index), true
+	case reflect.Map:
+		return lookupKey(s, v, strconv.Itoa(index))
+	default:
+		return reflect.Value{}, false
+	}
+}
+
+// lookupKey 在 v 中查找 key 对应的值，会自动解开接口与指针。
+// map 的 key 会转为 map 的 key 类型；结构体字段先精确匹配，再忽略大小写与下划线匹配
+func lookupKey(s *Scope, v reflect.Value, key string) (reflect.Value, bool) {
+	v = indirectValue(v)
+	if !v.IsValid() {
+		return reflect.Value{}, false
+	}
+	switch v.Kind() {
+	case reflect.Map:
+		keyType := v.Type().Key()
+		var k reflect.Value
+		if keyType.Kind() == reflect.String {
+			k = reflect.ValueOf(key).Convert(keyType)
+		} else {
+			var err error
+			if k, err = ReflectCastWithScope(s, reflect.ValueOf(key), keyType); err != nil {
+				return reflect.Value{}, false
+			}
+		}
+		elem := v.MapIndex(k)
+		return elem, elem.IsValid()
+	case reflect.Struct:
+		fields := getAllFields(s, v.Type())
+		field, ok := fields.byActualName[key]
+		if !ok {
+			if field, ok = fields.byFoldedName[foldNameStr(key)]; !ok {
+				return reflect.Value{}, false
+			}
+		}
+		addr := field.getAddr(getValueAddr(v), false)
+		if addr == nil {
+			return reflect.Value{}, false
+		}
+		return reflect.NewAt(field.typ, addr).Elem(), true
+	default:
+		return reflect.Value{}, false
+	}
+}
+
+// lookupPath 依次按 path 里的 key 查找，找不到时返回 false
+func lookupPath(s *Scope, v reflect.Value, path []string) (reflect.Value, bool) {
+	for _, key := range path {
+		var ok bool
+		if v, ok = lookupKey(s, v, key); !ok {
+			return reflect.Value{}, false
+		}
+	}
+	return v, true
+}
+
+// indirectValue 解开接口与指针，遇到 nil 时返回无效值
+func indirectValue(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// castPathValue 按 path 查找值并转换到字段里，未找到时返回 false
+func castPathValue(s *Scope, root reflect.Value, path []string, field *structField, toAddr unsafe.Pointer) (bool, error) {
+	v, ok := lookupPath(s, root, path)
+	if !ok {
+		return false, nil
+	}
+	if v.Kind() == reflect.Interface && v.IsNil() {
+		return true, nil
+	}
+	return true, castValue(s, v, field.typ, field.getAddr(toAddr, true))
+}
+
+// castValue 将反射值 v 转为 toType 写入 toAddr，会解开接口，toAddr 需指向零值
+func castValue(s *Scope, v reflect.Value, toType reflect.Type, toAddr unsafe.Pointer) error {
+	if v.IsValid() && v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		if s.strictNilCheck && !isNilableType(toType) {
+			return invalidCastErr(s, nil, toType)
+		}
+		return nil
+	}
+	caster, flag := getCaster(s, v.Type(), toType)
+	if caster == nil {
+		return invalidCastErr(s, v.Type(), toType)
+	}
+	addr := getValueAddr(v)
+	if isHasRef(flag) && v.CanAddr() {
+		// 源值可能在 map 内部或只读内存里，拷贝一份避免引用
+		addr = copyObject(v.Type(), addr)
+	}
+	return caster(addr, toAddr)
+}
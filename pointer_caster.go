@@ -22,7 +22,8 @@ func getPointerCaster(s *Scope, fromType, toType reflect.Type) (castFunc, uint8)
 	default:
 		fromDepth, fromElemType := getFinalElem(fromType) // fromDepth >= 0
 		toDepth, toElemType := getFinalElem(toType)       // toDepth >= 1
-		if isRefAble(s, fromElemType, toElemType) {
+		// 含有 deep 字段时需要拷贝指向的值
+		if isRefAble(s, fromElemType, toElemType) && !hasDeepField(s, fromElemType) && !hasDeepField(s, toElemType) {
 			if fromDepth < toDepth {
 				return func(fromAddr, toAddr unsafe.Pointer) error {
 					for d := toDepth - 1; d > fromDepth; d-- {
@@ -0,0 +1,221 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package cast
+
+import (
+	"reflect"
+	"strconv"
+	"unsafe"
+)
+
+// getIndexedFields 获取配置了 index 的字段，下标即位置，未使用的位置为 nil。没有字段配置 index 时返回 nil
+func getIndexedFields(s *Scope, typ reflect.Type) ([]*structField, error) {
+	fields := getAllFields(s, typ).flattened
+	length := 0
+	for _, field := range fields {
+		if !field.hasIndex {
+			continue
+		}
+		if field.index < 0 {
+			return nil, invalidIndexErr(typ, field.rawName)
+		}
+		if field.index >= length {
+			length = field.index + 1
+		}
+	}
+	if length == 0 {
+		return nil, nil
+	}
+	positional := make([]*structField, length)
+	for _, field := range fields {
+		if !field.hasIndex {
+			continue
+		}
+		if positional[field.index] != nil {
+			return nil, duplicateIndexErr(typ, field.rawName)
+		}
+		positional[field.index] = field
+	}
+	return positional, nil
+}
+
+// getPositionalFields 获取结构体按位置排列的字段，任一字段配置了 index 时同 getIndexedFields，否则按字段顺序排列。
+// 用于 FromRecords 等明确按位置转换的场景
+func getPositionalFields(s *Scope, typ reflect.Type) ([]*structField, error) {
+	fields, err := getIndexedFields(s, typ)
+	if fields == nil && err == nil {
+		fields = getAllFields(s, typ).flattened
+	}
+	return fields, err
+}
+
+// getSeqFields 获取与 slice/array 互转时按位置排列的字段，开启 WithPositional 时同 getPositionalFields，否则同 getIndexedFields
+func getSeqFields(s *Scope, typ reflect.Type) ([]*structField, error) {
+	if s.positional {
+		return getPositionalFields(s, typ)
+	}
+	return getIndexedFields(s, typ)
+}
+
+// getErrCaster 总是返回 err 的转换器，用于在转换时报告构建期发现的 tag 错误
+func getErrCaster(err error) (castFunc, uint8) {
+	return func(fromAddr, toAddr unsafe.Pointer) error {
+		return err
+	}, 0
+}
+
+// getStructToSeqCaster 结构体按位置转为 slice/array，array 长度不足时截断，超出的部分为零值。
+// 配置了 index 时只有这些字段参与转换；没有字段配置 index 时，仅在开启 WithPositional 时按字段顺序转换
+func getStructToSeqCaster(s *Scope, fromType, toType reflect.Type) (castFunc, uint8) {
+	fields, err := getSeqFields(s, fromType)
+	if err != nil {
+		return getErrCaster(err)
+	}
+	if len(fields) == 0 {
+		return nil, 0
+	}
+	isArray := toType.Kind() == reflect.Array
+	length := len(fields)
+	if isArray {
+		length = min(length, toType.Len())
+	}
+	toElemType := toType.Elem()
+	var flag uint8
+	casters := make([]castFunc, length)
+	for i := 0; i < length; i++ {
+		if fields[i] == nil {
+			continue
+		}
+		caster, fFlag := getCaster(s, fields[i].typ, toElemType)
+		if caster == nil {
+			return nil, 0
+		}
+		casters[i] = caster
+		flag |= fFlag
+	}
+	toElemSize := toElemType.Size()
+	zeroPtr := getZeroPtr(toType)
+	return func(fromAddr, toAddr unsafe.Pointer) error {
+		data := toAddr
+		if !isArray {
+			to := makeSlice(toElemType, length, length)
+			data = to.data
+			*(*slice)(toAddr) = to
+		}
+		for i := 0; i < length; i++ {
+			if casters[i] == nil {
+				continue
+			}
+			fromFieldAddr := fields[i].getAddr(fromAddr, false)
+			if fromFieldAddr == nil {
+				continue
+			}
+			if err := casters[i](fromFieldAddr, offset(data, i, toElemSize)); err != nil {
+				typedMemMove(typePtr(toType), toAddr, zeroPtr)
+				return err
+			}
+		}
+		return nil
+	}, flag
+}
+
+// getSeqToStructCaster slice/array 按位置转为结构体，字段的位置同 getStructToSeqCaster。
+// 没有字段对应的元素会被忽略（开启 WithErrorUnused 时报错），缺少的元素对应的字段按 required、default 与 WithErrorUnset 的规则处理
+func getSeqToStructCaster(s *Scope, fromType, toType reflect.Type) (castFunc, uint8) {
+	type metaField struct {
+		*structField
+		caster        castFunc
+		defaultCaster castFunc
+	}
+	fields, err := getSeqFields(s, toType)
+	if err != nil {
+		return getErrCaster(err)
+	}
+	if len(fields) == 0 {
+		return nil, 0
+	}
+	fromElemType := fromType.Elem()
+	isArray := fromType.Kind() == reflect.Array
+	var flag uint8
+	metaFields := make([]metaField, len(fields))
+	for i, field := range fields {
+		if field == nil {
+			continue
+		}
+		caster, fFlag := getCaster(s, fromElemType, field.typ)
+		if caster == nil {
+			return nil, 0
+		}
+		if isArray {
+			// slice 的元素本身就在堆上，只有 array 需要关注是否引用了源地址
+			flag |= fFlag
+		}
+		defaultCaster, ok := getDefaultCaster(s, field)
+		if !ok {
+			return nil, 0
+		}
+		metaFields[i] = metaField{
+			structField:   field,
+			caster:        caster,
+			defaultCaster: defaultCaster,
+		}
+	}
+	arrayLen := 0
+	if isArray {
+		arrayLen = fromType.Len()
+	}
+	setDefaults := getDefaultsSetter(toType)
+	fromElemSize := fromElemType.Size()
+	zeroPtr := getZeroPtr(toType)
+	return func(fromAddr, toAddr unsafe.Pointer) error {
+		data, length := fromAddr, arrayLen
+		if !isArray {
+			from := *(*slice)(fromAddr)
+			data, length = from.data, from.len
+		}
+		if setDefaults != nil {
+			setDefaults(toAddr)
+		}
+		var sc strictCollector
+		for i := range metaFields {
+			field := &metaFields[i]
+			if field.structField == nil {
+				continue
+			}
+			if i < length {
+				err := field.caster(offset(data, i, fromElemSize), field.getAddr(toAddr, true))
+				if err != nil && !sc.merge(err, "["+strconv.Itoa(i)+"]", field.rawName) {
+					typedMemMove(typePtr(toType), toAddr, zeroPtr)
+					return err
+				}
+				continue
+			}
+			if field.isRequired {
+				typedMemMove(typePtr(toType), toAddr, zeroPtr)
+				return requiredFieldNotMatchErr(toType, field.rawName)
+			}
+			if s.errorUnset && isUnsetField(field.structField, field.defaultCaster, setDefaults, toAddr) {
+				sc.unset = append(sc.unset, field.rawName)
+			}
+			if err := setDefault(field.structField, field.defaultCaster, toAddr); err != nil {
+				typedMemMove(typePtr(toType), toAddr, zeroPtr)
+				return err
+			}
+		}
+		if s.errorUnused {
+			for i := 0; i < length; i++ {
+				if i >= len(metaFields) || metaFields[i].structField == nil {
+					sc.unused = append(sc.unused, "["+strconv.Itoa(i)+"]")
+				}
+			}
+		}
+		if err := sc.err(fromType, toType); err != nil {
+			typedMemMove(typePtr(toType), toAddr, zeroPtr)
+			return err
+		}
+		return nil
+	}, flag
+}
@@ -0,0 +1,191 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package cast
+
+import (
+	"fmt"
+	"reflect"
+	"unsafe"
+)
+
+// FromRecords 将 CSV 记录（如 encoding/csv 的输出）转为 []T。
+// header 为 true 时第一行为表头，按表头匹配字段（规则与 map 转 struct 一致），否则按位置匹配（任一字段配置了 index 时按 index，否则按字段顺序）。
+// 空单元格视为缺失，错误信息里会带上行号与列号（从 1 开始，包括表头）
+func FromRecords[T any](records [][]string, header bool) ([]T, error) {
+	return FromRecordsWithScope[T](defaultScope, records, header)
+}
+
+// FromRecordsWithScope 类似于 FromRecords，使用作用域 s 里的转换器
+func FromRecordsWithScope[T any](s *Scope, records [][]string, header bool) ([]T, error) {
+	if len(records) == 0 {
+		return nil, nil
+	}
+	toType := typeFor[T]()
+	var names []string
+	if header {
+		names, records = records[0], records[1:]
+	}
+	rowOffset := 1
+	if header {
+		rowOffset = 2
+	}
+	items := make([]T, len(records))
+	if toType.Kind() != reflect.Struct {
+		// 非结构体整行转换，有表头时先转为 map[string]string
+		for i, record := range records {
+			var err error
+			if header {
+				m := make(map[string]string, len(names))
+				for j, name := range names {
+					if j < len(record) {
+						m[name] = record[j]
+					}
+				}
+				items[i], err = CastWithScope[map[string]string, T](s, m)
+			} else {
+				items[i], err = CastWithScope[[]string, T](s, record)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("row %d: %w", i+rowOffset, err)
+			}
+		}
+		return items, nil
+	}
+
+	type column struct {
+		field  *structField
+		caster castFunc
+	}
+	var fields []*structField
+	if header {
+		allFields := getAllFields(s, toType)
+		fields = make([]*structField, len(names))
+		for j, name := range names {
+			field, ok := allFields.byActualName[name]
+			if !ok {
+				field, ok = allFields.byFoldedName[foldNameStr(name)]
+			}
+			if ok {
+				fields[j] = field
+			}
+		}
+	} else {
+		var err error
+		if fields, err = getPositionalFields(s, toType); err != nil {
+			return nil, err
+		}
+	}
+	columns := make([]column, len(fields))
+	for j, field := range fields {
+		if field == nil {
+			continue
+		}
+		caster, _ := getCaster(s, stringType, field.typ)
+		if caster == nil {
+			return nil, fmt.Errorf("column %d: %w", j+1, invalidCastErr(s, stringType, field.typ))
+		}
+		columns[j] = column{field, caster}
+	}
+	setDefaults := getDefaultsSetter(toType)
+	allFields := getAllFields(s, toType).flattened
+	defaultCasters := make([]castFunc, len(allFields))
+	for k, field := range allFields {
+		defaultCaster, ok := getDefaultCaster(s, field)
+		if !ok {
+			return nil, invalidCastErr(s, stringType, field.typ)
+		}
+		defaultCasters[k] = defaultCaster
+	}
+	set := make(map[*structField]struct{}, len(allFields))
+	for i, record := range records {
+		toAddr := unsafe.Pointer(&items[i])
+		if setDefaults != nil {
+			setDefaults(toAddr)
+		}
+		for k := range set {
+			delete(set, k)
+		}
+		for j, cell := range record {
+			if j >= len(columns) || columns[j].field == nil || cell == "" {
+				continue
+			}
+			col := &columns[j]
+			v := cell
+			if err := col.caster(unsafe.Pointer(&v), col.field.getAddr(toAddr, true)); err != nil {
+				return nil, fmt.Errorf("row %d, column %d (%s): %w", i+rowOffset, j+1, col.field.name, err)
+			}
+			set[col.field] = struct{}{}
+		}
+		for k, field := range allFields {
+			if _, ok := set[field]; ok {
+				continue
+			}
+			if field.isRequired {
+				return nil, fmt.Errorf("row %d: %w", i+rowOffset, requiredFieldNotMatchErr(toType, field.rawName))
+			}
+			if err := setDefault(field, defaultCasters[k], toAddr); err != nil {
+				return nil, fmt.Errorf("row %d (%s): %w", i+rowOffset, field.name, err)
+			}
+		}
+	}
+	return items, nil
+}
+
+// ToRecords 将 []T 转为 CSV 记录，T 需为结构体，字段按位置排列（任一字段配置了 index 时按 index，否则按字段顺序），值通过作用域里转为 string 的转换器转换。
+// header 为 true 时第一行为字段名
+func ToRecords[T any](items []T, header bool) ([][]string, error) {
+	return ToRecordsWithScope[T](defaultScope, items, header)
+}
+
+// ToRecordsWithScope 类似于 ToRecords，使用作用域 s 里的转换器
+func ToRecordsWithScope[T any](s *Scope, items []T, header bool) ([][]string, error) {
+	fromType := typeFor[T]()
+	if fromType.Kind() != reflect.Struct {
+		return nil, invalidCastErr(s, fromType, typeFor[[]string]())
+	}
+	fields, err := getPositionalFields(s, fromType)
+	if err != nil {
+		return nil, err
+	}
+	casters := make([]castFunc, len(fields))
+	for j, field := range fields {
+		if field == nil {
+			continue
+		}
+		if casters[j], _ = getCaster(s, field.typ, stringType); casters[j] == nil {
+			return nil, fmt.Errorf("column %d: %w", j+1, invalidCastErr(s, field.typ, stringType))
+		}
+	}
+	records := make([][]string, 0, len(items)+1)
+	if header {
+		names := make([]string, len(fields))
+		for j, field := range fields {
+			if field != nil {
+				names[j] = field.name
+			}
+		}
+		records = append(records, names)
+	}
+	rowOffset := len(records) + 1
+	for i := range items {
+		fromAddr := unsafe.Pointer(&items[i])
+		record := make([]string, len(fields))
+		for j, field := range fields {
+			if field == nil {
+				continue
+			}
+			fromFieldAddr := field.getAddr(fromAddr, false)
+			if fromFieldAddr == nil {
+				continue
+			}
+			if err := casters[j](fromFieldAddr, noEscape(unsafe.Pointer(&record[j]))); err != nil {
+				return nil, fmt.Errorf("row %d, column %d (%s): %w", i+rowOffset, j+1, field.name, err)
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
@@ -8,20 +8,44 @@ package cast
 import (
 	"reflect"
 	"sync"
+	"sync/atomic"
 	"unsafe"
 )
 
 type Scope struct {
 	casterCache          cache
 	casterMap            map[casterKey]casterValue
-	mu                   sync.RWMutex // 读多写少的场景，sync.RWMutex的效率比sync.Map更高
+	building             map[casterKey]*pendingCaster // 正在构建的转换器，用于支持递归类型
+	mu                   sync.RWMutex                 // 读多写少的场景，sync.RWMutex的效率比sync.Map更高
 	frozen               bool
 	definedFromAnyCaster bool
+	options              []ScopeOption      // 创建作用域时传入的选项，用于派生新的作用域
+	collectMetadata      bool               // 转为结构体的转换器是否在运行时记录字段匹配情况
+	metadata             *metadataCollector // 本次转换的记录器，仅在 collectMetadata 时由 CastWithMetadata 设置
+	metadataOwner        *Scope             // 派生出的 shallow、deep 字段作用域使用所属作用域的记录器
+	metadataOnce         sync.Once
+	metadataScope        *Scope     // 开启了 collectMetadata 的派生作用域，用于 CastWithMetadata
+	metadataMu           sync.Mutex // 保证同一时刻只有一次转换使用 metadata
+	copyModeOnce         sync.Once
+	copyModeScope        *Scope // 切换了深拷贝的派生作用域，用于获取 shallow、deep 字段的转换器
+	deepMappingOnce      sync.Once
+	deepMappingScope     *Scope                                  // 开启了深度映射的派生作用域，用于 ToMap
+	deepFieldTypes       atomic.Pointer[map[unsafe.Pointer]bool] // 各类型是否有 deep 字段，写时复制，供 ToWithScope 无锁读取
 
-	disableZeroCopy bool // 禁用零拷贝
-	deepCopy        bool // 深拷贝
-	castUnexported  bool // 转换未导出字段
-	strictNilCheck  bool // 仅允许 nil 转为可以为 nil 的类型
+	disableZeroCopy  bool                            // 禁用零拷贝
+	deepCopy         bool                            // 深拷贝
+	castUnexported   bool                            // 转换未导出字段
+	strictNilCheck   bool                            // 仅允许 nil 转为可以为 nil 的类型
+	omitEmpty        bool                            // 结构体转 map 时忽略所有空值字段
+	omitZero         bool                            // 结构体转 map 时忽略所有零值字段
+	errorUnused      bool                            // 转为结构体时，源 map 的 key 或源结构体的字段未被使用则报错
+	errorUnset       bool                            // 转为结构体时，目标结构体的字段未被赋值则报错
+	deepMapping      bool                            // 转为空接口时，递归地把结构体/map 转为 map[string]any，slice/array 转为 []any
+	mergeAppendSlice bool                            // Merge 时 slice 追加而不是整体替换
+	refTracking      bool                            // 深拷贝时保持指针、map、slice 的共享关系与循环引用
+	deepCopyPolicies map[reflect.Kind]DeepCopyPolicy // 深拷贝 chan、func、unsafe.Pointer 时的策略
+	copyAtomicValues bool                            // 拷贝 sync/atomic 里的类型时原子地拷贝值，而不是置零
+	positional       bool                            // 没有字段配置 index 的结构体也按字段顺序与 slice/array 互转
 }
 
 func (s *Scope) DisableZeroCopy() bool {
@@ -40,12 +64,54 @@ func (s *Scope) StrictNilCheck() bool {
 	return s.strictNilCheck
 }
 
+func (s *Scope) OmitEmpty() bool {
+	return s.omitEmpty
+}
+
+func (s *Scope) OmitZero() bool {
+	return s.omitZero
+}
+
+func (s *Scope) ErrorUnused() bool {
+	return s.errorUnused
+}
+
+func (s *Scope) ErrorUnset() bool {
+	return s.errorUnset
+}
+
+func (s *Scope) DeepMapping() bool {
+	return s.deepMapping
+}
+
+func (s *Scope) MergeAppendSlice() bool {
+	return s.mergeAppendSlice
+}
+
+func (s *Scope) RefTracking() bool {
+	return s.refTracking
+}
+
+func (s *Scope) DeepCopyPolicy(kind reflect.Kind) DeepCopyPolicy {
+	return s.deepCopyPolicies[kind]
+}
+
+func (s *Scope) CopyAtomicValues() bool {
+	return s.copyAtomicValues
+}
+
+func (s *Scope) Positional() bool {
+	return s.positional
+}
+
 type ScopeOption func(s *Scope)
 
 // NewScope 创建新的作用域
 func NewScope(options ...ScopeOption) *Scope {
 	scope := &Scope{
 		casterMap: make(map[casterKey]casterValue),
+		building:  make(map[casterKey]*pendingCaster),
+		options:   options,
 	}
 	for _, option := range defaultOptions {
 		option(scope)
@@ -57,6 +123,72 @@ func NewScope(options ...ScopeOption) *Scope {
 	return scope
 }
 
+// derive 以当前作用域的选项为基础，追加 options 创建新的作用域
+func (s *Scope) derive(options ...ScopeOption) *Scope {
+	derived := make([]ScopeOption, 0, len(s.options)+len(options))
+	derived = append(derived, s.options...)
+	derived = append(derived, options...)
+	return NewScope(derived...)
+}
+
+// forCopyMode 获取按字段的拷贝方式转换时使用的作用域：深拷贝时 shallow 字段不深拷贝，非深拷贝时 deep 字段深拷贝
+func (s *Scope) forCopyMode(copyMode uint8) *Scope {
+	if copyMode == copyDefault || (copyMode == copyDeep) == s.deepCopy {
+		return s
+	}
+	s.copyModeOnce.Do(func() {
+		if s.deepCopy {
+			s.copyModeScope = s.derive(withoutDeepCopy())
+		} else {
+			s.copyModeScope = s.derive(WithDeepCopy())
+		}
+		if s.collectMetadata {
+			s.copyModeScope.metadataOwner = s
+		}
+	})
+	return s.copyModeScope
+}
+
+// forDeepMapping 获取开启了深度映射的作用域，s 未开启时基于 s 的选项派生
+func (s *Scope) forDeepMapping() *Scope {
+	if s.deepMapping {
+		return s
+	}
+	s.deepMappingOnce.Do(func() {
+		s.deepMappingScope = s.derive(WithDeepMapping())
+	})
+	return s.deepMappingScope
+}
+
+// hasDeepField 同 hasDeepField，结果按类型缓存在作用域里，使得 ToWithScope 的快速路径只需一次无锁的 map 查找
+func (s *Scope) hasDeepField(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.Array, reflect.Struct, reflect.Slice, reflect.Pointer, reflect.Map:
+	default:
+		return false
+	}
+	key := typePtr(typ)
+	if m := s.deepFieldTypes.Load(); m != nil {
+		if v, ok := (*m)[key]; ok {
+			return v
+		}
+	}
+	v := hasDeepField(s, typ)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var old map[unsafe.Pointer]bool
+	if m := s.deepFieldTypes.Load(); m != nil {
+		old = *m
+	}
+	m := make(map[unsafe.Pointer]bool, len(old)+1)
+	for k, ov := range old {
+		m[k] = ov
+	}
+	m[key] = v
+	s.deepFieldTypes.Store(&m)
+	return v
+}
+
 var defaultScope = NewScope()
 
 // SetDefaultScope ！！慎用！！设置默认作用域，可以改变默认行为
@@ -70,7 +202,7 @@ func ToWithScope[T any](s *Scope, from any) (to T, err error) {
 		return CastWithScope[any, T](s, from)
 	}
 	toType := typeFor[T]()
-	if !s.deepCopy || !isRefType(toType) {
+	if !s.deepMapping && (!s.deepCopy || !isRefType(toType)) && !s.hasDeepField(toType) {
 		if tmp, ok := from.(T); ok {
 			return tmp, nil
 		}
@@ -238,6 +370,17 @@ func WithDeepCopy() ScopeOption {
 	}
 }
 
+// withoutDeepCopy 关闭深拷贝，用于派生 shallow 字段的作用域
+func withoutDeepCopy() ScopeOption {
+	return func(s *Scope) {
+		if s.frozen {
+			return
+		}
+		s.deepCopy = false
+		s.refTracking = false
+	}
+}
+
 // WithUnexportedFields 转换结构体的未导出字段
 func WithUnexportedFields() ScopeOption {
 	return func(s *Scope) {
@@ -257,3 +400,129 @@ func WithStrictNilCheck() ScopeOption {
 		s.strictNilCheck = true
 	}
 }
+
+// WithOmitEmpty 结构体转 map 时，所有字段均视为配置了 omitempty
+func WithOmitEmpty() ScopeOption {
+	return func(s *Scope) {
+		if s.frozen {
+			return
+		}
+		s.omitEmpty = true
+	}
+}
+
+// WithOmitZero 结构体转 map 时，所有字段均视为配置了 omitzero
+func WithOmitZero() ScopeOption {
+	return func(s *Scope) {
+		if s.frozen {
+			return
+		}
+		s.omitZero = true
+	}
+}
+
+// WithErrorUnused map/struct 转为结构体时，若源 map 存在未匹配到字段的 key，或源结构体存在未匹配到的字段，则转换失败
+func WithErrorUnused() ScopeOption {
+	return func(s *Scope) {
+		if s.frozen {
+			return
+		}
+		s.errorUnused = true
+	}
+}
+
+// WithErrorUnset map/struct 转为结构体时，若目标结构体存在未被赋值的字段（使用默认值的字段视为已赋值），则转换失败
+func WithErrorUnset() ScopeOption {
+	return func(s *Scope) {
+		if s.frozen {
+			return
+		}
+		s.errorUnset = true
+	}
+}
+
+// WithDeepMapping 转为空接口（如 map[string]any 的值）时，递归地把结构体与 map 转为 map[string]any、slice 与 array 转为 []any，
+// 指针转为其指向的值（nil 转为 nil），得到纯粹的类 JSON 数据
+func WithDeepMapping() ScopeOption {
+	return func(s *Scope) {
+		if s.frozen {
+			return
+		}
+		s.deepMapping = true
+	}
+}
+
+// WithMergeAppendSlice Merge 时，后面的源里的 slice 追加到已有的 slice 后面，而不是整体替换
+func WithMergeAppendSlice() ScopeOption {
+	return func(s *Scope) {
+		if s.frozen {
+			return
+		}
+		s.mergeAppendSlice = true
+	}
+}
+
+// WithRefTracking 与 WithDeepCopy 一起使用，同类型深拷贝时记录已拷贝的指针、map 与 slice 的底层数组，
+// 拷贝前共享同一对象的引用在拷贝后仍然共享同一个新对象，循环引用（如双向链表）也能被还原
+func WithRefTracking() ScopeOption {
+	return func(s *Scope) {
+		if s.frozen {
+			return
+		}
+		s.refTracking = true
+	}
+}
+
+// DeepCopyPolicy 深拷贝无法真正拷贝的类型（chan、func、unsafe.Pointer）时的策略
+type DeepCopyPolicy uint8
+
+const (
+	DeepCopyDefault DeepCopyPolicy = iota // 默认行为：chan 报错，func 包装为调用原函数的新函数，unsafe.Pointer 共享
+	DeepCopyShare                         // 共享同一个引用
+	DeepCopyZero                          // 拷贝为零值
+	DeepCopyError                         // 报错
+)
+
+// WithDeepCopyPolicy 设置深拷贝 kind 类型（仅支持 reflect.Chan、reflect.Func、reflect.UnsafePointer，其他 kind 会被忽略）时的策略，
+// 如 WithDeepCopyPolicy(reflect.Chan, DeepCopyShare) 使得含有 chan 字段的结构体也能深拷贝。DeepCopyError 只对非 nil 的值报错
+func WithDeepCopyPolicy(kind reflect.Kind, policy DeepCopyPolicy) ScopeOption {
+	return func(s *Scope) {
+		if s.frozen {
+			return
+		}
+		switch kind {
+		case reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		default:
+			return
+		}
+		// 拷贝一份，避免修改派生前作用域的 map
+		policies := make(map[reflect.Kind]DeepCopyPolicy, len(s.deepCopyPolicies)+1)
+		for k, v := range s.deepCopyPolicies {
+			policies[k] = v
+		}
+		policies[kind] = policy
+		s.deepCopyPolicies = policies
+	}
+}
+
+// WithCopyAtomicValues 拷贝结构体里的 sync/atomic 类型（如 atomic.Int64、atomic.Value）时，通过 Load、Store 原子地拷贝其值。
+// 默认情况下，sync.Mutex、sync.WaitGroup、atomic.Int64 等同步原语以及 noCopy 标记类型都会被拷贝为零值
+func WithCopyAtomicValues() ScopeOption {
+	return func(s *Scope) {
+		if s.frozen {
+			return
+		}
+		s.copyAtomicValues = true
+	}
+}
+
+// WithPositional 没有字段配置 index 的结构体与 slice/array 互转时，按字段的声明顺序（结构体自身的字段在前，展开的匿名/内联字段在后）一一对应。
+// 默认只有配置了 index 的结构体按位置转换
+func WithPositional() ScopeOption {
+	return func(s *Scope) {
+		if s.frozen {
+			return
+		}
+		s.positional = true
+	}
+}
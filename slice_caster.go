@@ -91,6 +91,11 @@ func getSliceCaster(s *Scope, fromType, toType reflect.Type) (castFunc, uint8) {
 		default:
 			return nil, 0
 		}
+	case reflect.Struct:
+		if toType.Elem() == kvType {
+			return getStructToKVsCaster(s, fromType)
+		}
+		return getStructToSeqCaster(s, fromType, toType)
 	default:
 		return nil, 0
 	}
@@ -7,9 +7,62 @@ package cast
 
 import (
 	"reflect"
+	"sort"
 	"unsafe"
 )
 
+// Defaulter 目标结构体（的指针）实现该接口时，map/struct 转为该结构体前会先调用 SetDefaults 设置默认值
+type Defaulter interface {
+	SetDefaults()
+}
+
+// getDefaultsSetter 若 *T 实现了 Defaulter，返回调用 SetDefaults 的函数，否则返回 nil
+func getDefaultsSetter(typ reflect.Type) func(addr unsafe.Pointer) {
+	ptrType := reflect.PointerTo(typ)
+	if !ptrType.Implements(defaulterType) {
+		return nil
+	}
+	return func(addr unsafe.Pointer) {
+		packEface(ptrType, addr).(Defaulter).SetDefaults()
+	}
+}
+
+// isUnsetField 未匹配到源 key/字段的字段是否视为未被赋值：配置了 default，或者被 Defaulter.SetDefaults 赋为非零值的字段视为已赋值
+func isUnsetField(field *structField, defaultCaster castFunc, setDefaults func(addr unsafe.Pointer), toAddr unsafe.Pointer) bool {
+	if defaultCaster != nil {
+		return false
+	}
+	if setDefaults == nil {
+		return true
+	}
+	addr := field.getAddr(toAddr, false)
+	return addr == nil || reflect.NewAt(field.typ, addr).Elem().IsZero()
+}
+
+// getDefaultCaster 获取字段默认值的转换器，字段未配置默认值时返回 nil, true；默认值无法转为字段类型时返回 nil, false
+func getDefaultCaster(s *Scope, field *structField) (castFunc, bool) {
+	if !field.hasDefault {
+		return nil, true
+	}
+	caster, _ := getCaster(s, stringType, field.typ)
+	return caster, caster != nil
+}
+
+func isUsed(used map[string]struct{}, key string) bool {
+	_, ok := used[key]
+	return ok
+}
+
+// setDefault 将默认值写入字段，defaultCaster 为 nil 时不做任何处理
+func setDefault(field *structField, defaultCaster castFunc, toAddr unsafe.Pointer) error {
+	if defaultCaster == nil {
+		return nil
+	}
+	// 拷贝一份，避免零拷贝转换后指向 tag 的只读内存
+	v := cloneString(field.defaultVal)
+	return defaultCaster(noEscape(unsafe.Pointer(&v)), field.getAddr(toAddr, true))
+}
+
 func getStructCaster(s *Scope, fromType, toType reflect.Type) (castFunc, uint8) {
 	switch fromType.Kind() {
 	case reflect.Interface:
@@ -24,31 +77,65 @@ func getStructCaster(s *Scope, fromType, toType reflect.Type) (castFunc, uint8)
 		// 转换步骤：在线把map[K]V先转为map[string]V，再匹配字段
 		type metaField struct {
 			structField
-			caster castFunc
-			flag   uint8
+			caster        castFunc
+			flag          uint8
+			defaultCaster castFunc
 		}
 		fields := getAllFields(s, toType)
-		if len(fields.flattened) == 0 {
+		setDefaults := getDefaultsSetter(toType)
+		if len(fields.flattened) == 0 && fields.remain == nil && setDefaults == nil && !s.errorUnused && !s.collectMetadata {
 			return func(fromAddr, toAddr unsafe.Pointer) error {
 				return nil
 			}, 0
 		}
+		// 多值 map（如 url.Values、http.Header）的非序列字段优先取第一个值，空接口字段除外
+		isMultiValue := fromElemType.Kind() == reflect.Slice && fromElemType.Elem().Kind() == reflect.String
 		metaFields := make([]metaField, 0, len(fields.flattened))
 		for _, field := range fields.flattened {
-			caster, flag := getCaster(s, fromElemType, field.typ)
+			var caster castFunc
+			var flag uint8
+			if isMultiValue && field.typ.Kind() != reflect.Interface {
+				caster, flag = getFirstElemCaster(s.forCopyMode(field.copyMode), fromElemType, field.typ)
+			}
+			if caster == nil {
+				caster, flag = getCaster(s.forCopyMode(field.copyMode), fromElemType, field.typ)
+			}
 			if caster == nil && field.isRequired {
 				return nil, 0
 			}
+			defaultCaster, ok := getDefaultCaster(s, field)
+			if !ok {
+				return nil, 0
+			}
 			metaFields = append(metaFields, metaField{
-				structField: *field,
-				caster:      caster,
-				flag:        flag,
+				structField:   *field,
+				caster:        caster,
+				flag:          flag,
+				defaultCaster: defaultCaster,
 			})
 		}
+		// remain 字段接收所有未匹配的 key
+		remain := fields.remain
+		var remainCaster castFunc
+		var remainFlag uint8
+		var remainElemType reflect.Type
+		var remainMapHelper *mapHelper
+		if remain != nil {
+			remainElemType = remain.typ.Elem()
+			remainCaster, remainFlag = getCaster(s, fromElemType, remainElemType)
+			if remainCaster == nil {
+				return nil, 0
+			}
+			remainMapHelper = newMapHelper(remain.typ)
+		}
 		keyIsStr := fromKeyType.Kind() == reflect.String
 		fromMapHelper := newMapHelper(fromType)
 		zeroPtr := getZeroPtr(toType)
 		return func(fromAddr, toAddr unsafe.Pointer) error {
+			md := s.getMetadata()
+			if setDefaults != nil {
+				setDefaults(toAddr)
+			}
 			from := *(*map[any]any)(fromAddr)
 			var keyMap map[string]unsafe.Pointer
 			if !keyIsStr {
@@ -64,6 +151,13 @@ func getStructCaster(s *Scope, fromType, toType reflect.Type) (castFunc, uint8)
 					return true
 				})
 			}
+			// 记录被字段使用了的 key，仅在需要时记录
+			var used map[string]struct{}
+			if remain != nil || s.errorUnused || md != nil {
+				used = make(map[string]struct{}, len(metaFields))
+			}
+			// 未被使用的 key 与未被赋值的字段，包括嵌套结构体里的，仅在 s.errorUnused、s.errorUnset 时记录
+			var sc strictCollector
 			var missField []*metaField
 			for i := range metaFields {
 				field := &metaFields[i]
@@ -74,8 +168,41 @@ func getStructCaster(s *Scope, fromType, toType reflect.Type) (castFunc, uint8)
 				} else {
 					v, ok = keyMap[field.name]
 				}
+				if !ok && field.keyPath != nil {
+					// 字面量 key 不存在时，按路径访问嵌套的值
+					first := field.keyPath[0]
+					if keyIsStr {
+						v, ok = fromMapHelper.Load(from, unsafe.Pointer(&first))
+					} else {
+						v, ok = keyMap[first]
+					}
+					if ok {
+						if md != nil {
+							md.push(field.name)
+						}
+						root := reflect.NewAt(fromElemType, v).Elem()
+						found, err := castPathValue(s, root, field.keyPath[1:], &field.structField, toAddr)
+						if md != nil {
+							md.pop()
+						}
+						if err != nil && !sc.merge(err, field.name, field.rawName) {
+							typedMemMove(typePtr(toType), toAddr, zeroPtr)
+							return err
+						}
+						if found {
+							if used != nil {
+								used[first] = struct{}{}
+							}
+							if md != nil {
+								md.addKey(field.name, false)
+							}
+							continue
+						}
+						ok = false
+					}
+				}
 				if !ok {
-					if field.foldedName != "" && field.foldedName != field.name {
+					if field.foldedName != "" {
 						missField = append(missField, field)
 						continue
 					}
@@ -83,69 +210,209 @@ func getStructCaster(s *Scope, fromType, toType reflect.Type) (castFunc, uint8)
 						typedMemMove(typePtr(toType), toAddr, zeroPtr)
 						return requiredFieldNotMatchErr(toType, field.rawName)
 					}
+					if (s.errorUnset || md != nil) && isUnsetField(&field.structField, field.defaultCaster, setDefaults, toAddr) {
+						if s.errorUnset {
+							sc.unset = append(sc.unset, field.rawName)
+						}
+						if md != nil {
+							md.addUnset(field.rawName)
+						}
+					}
+					if err := setDefault(&field.structField, field.defaultCaster, toAddr); err != nil {
+						typedMemMove(typePtr(toType), toAddr, zeroPtr)
+						return err
+					}
 					continue
 				}
+				if used != nil {
+					used[field.name] = struct{}{}
+				}
 				if field.caster == nil {
 					return invalidCastErr(s, fromElemType, field.typ)
 				}
 				if isHasRef(field.flag) {
 					v = copyObject(fromElemType, v)
 				}
-				if err := field.caster(v, field.getAddr(toAddr, true)); err != nil {
+				if md != nil {
+					md.addKey(field.name, false)
+					md.push(field.name)
+				}
+				err := field.caster(v, field.getAddr(toAddr, true))
+				if md != nil {
+					md.pop()
+				}
+				if err != nil && !sc.merge(err, field.name, field.rawName) {
 					typedMemMove(typePtr(toType), toAddr, zeroPtr)
 					return err
 				}
 			}
-			if len(missField) == 0 {
-				return nil
-			}
 
-			// 这里key不能排除foldNameStr(k)==k的，因为前面已经排除了field.foldedName==field.name的，比如存在以下情况：
-			// field.name="a", field.foldedName="A", k="A", foldNameStr(k)="A"
-			foldedKeyMap := make(map[string]unsafe.Pointer, len(from))
-			if !keyIsStr {
-				for k, v := range keyMap {
-					foldedKeyMap[foldNameStr(k)] = v
+			if len(missField) > 0 {
+				type foldedValue struct {
+					key   string
+					value unsafe.Pointer
 				}
-			} else {
-				fromMapHelper.Range(from, func(key, value unsafe.Pointer) bool {
-					foldedKeyMap[foldNameStr(*(*string)(key))] = value
-					return true
-				})
-			}
-			for _, field := range missField {
-				v, ok := foldedKeyMap[field.foldedName]
-				if !ok {
-					if field.isRequired {
+				// 这里key不能排除foldNameStr(k)==k的，比如存在以下情况：
+				// field.name="a", field.foldedName="A", k="A", foldNameStr(k)="A"
+				// 同样不能排除field.foldedName==field.name的字段，如 field.name="DB", k="db"
+				foldedKeyMap := make(map[string]foldedValue, len(from))
+				if !keyIsStr {
+					for k, v := range keyMap {
+						foldedKeyMap[foldNameStr(k)] = foldedValue{k, v}
+					}
+				} else {
+					fromMapHelper.Range(from, func(key, value unsafe.Pointer) bool {
+						k := *(*string)(key)
+						foldedKeyMap[foldNameStr(k)] = foldedValue{k, value}
+						return true
+					})
+				}
+				for _, field := range missField {
+					fv, ok := foldedKeyMap[field.foldedName]
+					if !ok {
+						if field.isRequired {
+							typedMemMove(typePtr(toType), toAddr, zeroPtr)
+							return requiredFieldNotMatchErr(toType, field.rawName)
+						}
+						if (s.errorUnset || md != nil) && isUnsetField(&field.structField, field.defaultCaster, setDefaults, toAddr) {
+							if s.errorUnset {
+								sc.unset = append(sc.unset, field.rawName)
+							}
+							if md != nil {
+								md.addUnset(field.rawName)
+							}
+						}
+						if err := setDefault(&field.structField, field.defaultCaster, toAddr); err != nil {
+							typedMemMove(typePtr(toType), toAddr, zeroPtr)
+							return err
+						}
+						continue
+					}
+					if used != nil {
+						used[fv.key] = struct{}{}
+					}
+					if field.caster == nil {
+						return invalidCastErr(s, fromElemType, field.typ)
+					}
+					v := fv.value
+					if isHasRef(field.flag) {
+						v = copyObject(fromElemType, v)
+					}
+					if md != nil {
+						md.addKey(fv.key, true)
+						md.push(fv.key)
+					}
+					err := field.caster(v, field.getAddr(toAddr, true))
+					if md != nil {
+						md.pop()
+					}
+					if err != nil && !sc.merge(err, fv.key, field.rawName) {
 						typedMemMove(typePtr(toType), toAddr, zeroPtr)
-						return requiredFieldNotMatchErr(toType, field.rawName)
+						return err
 					}
-					continue
 				}
-				if field.caster == nil {
-					return invalidCastErr(s, fromElemType, field.typ)
+			}
+
+			if remain != nil {
+				var err error
+				remainAddr := remain.getAddr(toAddr, true)
+				buffer := newObject(remainElemType)
+				store := func(key string, value unsafe.Pointer) bool {
+					if isUsed(used, key) {
+						return true
+					}
+					if md != nil {
+						md.addKey(key, false)
+					}
+					if isHasRef(remainFlag) {
+						value = copyObject(fromElemType, value)
+					}
+					typedMemMove(typePtr(remainElemType), buffer, getZeroPtr(remainElemType))
+					if err = remainCaster(value, buffer); err != nil {
+						return false
+					}
+					m := *(*map[any]any)(remainAddr)
+					if m == nil {
+						m = remainMapHelper.Make(len(from) - len(used))
+						*(*map[any]any)(remainAddr) = m
+					}
+					remainMapHelper.Store(m, noEscape(unsafe.Pointer(&key)), buffer)
+					return true
 				}
-				if isHasRef(field.flag) {
-					v = copyObject(fromElemType, v)
+				if keyIsStr {
+					fromMapHelper.Range(from, func(key, value unsafe.Pointer) bool {
+						return store(*(*string)(key), value)
+					})
+				} else {
+					for k, v := range keyMap {
+						if !store(k, v) {
+							break
+						}
+					}
 				}
-				if err := field.caster(v, field.getAddr(toAddr, true)); err != nil {
+				if err != nil {
 					typedMemMove(typePtr(toType), toAddr, zeroPtr)
 					return err
 				}
+			} else if s.errorUnused || md != nil {
+				var unused []string
+				if keyIsStr {
+					fromMapHelper.Range(from, func(key, value unsafe.Pointer) bool {
+						if k := *(*string)(key); !isUsed(used, k) {
+							unused = append(unused, k)
+						}
+						return true
+					})
+				} else {
+					for k := range keyMap {
+						if !isUsed(used, k) {
+							unused = append(unused, k)
+						}
+					}
+				}
+				sort.Strings(unused)
+				if md != nil {
+					for _, k := range unused {
+						md.addUnused(k)
+					}
+				}
+				if s.errorUnused {
+					sc.unused = append(sc.unused, unused...)
+				}
+			}
+			if err := sc.err(fromType, toType); err != nil {
+				typedMemMove(typePtr(toType), toAddr, zeroPtr)
+				return err
 			}
 			return nil
 		}, 0
 	case reflect.Pointer:
 		return getAddressingPointerCaster(s, fromType, toType)
+	case reflect.Slice:
+		if fromType.Elem() == kvType {
+			return getKVsCaster(s, toType)
+		}
+		return getSeqToStructCaster(s, fromType, toType)
+	case reflect.Array:
+		return getSeqToStructCaster(s, fromType, toType)
 	case reflect.Struct:
 		type metaField struct {
 			structField
 			fromField     structField
 			caster        castFunc
 			fromIsNilable bool
+			defaultCaster castFunc
+			folded        bool
+		}
+		type defaultField struct {
+			structField
+			defaultCaster castFunc
 		}
 		toFields := getAllFields(s, toType)
-		if len(toFields.flattened) == 0 {
+		// cast tag 为路径、且路径的第一段匹配到源字段的字段，运行时按路径查找
+		var pathFields []defaultField
+		setDefaults := getDefaultsSetter(toType)
+		if len(toFields.flattened) == 0 && toFields.remain == nil && setDefaults == nil && !s.errorUnused && !s.collectMetadata {
 			return func(fromAddr, toAddr unsafe.Pointer) error {
 				return nil
 			}, 0
@@ -153,18 +420,64 @@ func getStructCaster(s *Scope, fromType, toType reflect.Type) (castFunc, uint8)
 		fromFields := getAllFields(s, fromType)
 		var flag uint8
 		metaFields := make([]metaField, 0, len(toFields.flattened))
+		// 未匹配到源字段、但配置了默认值的字段
+		var defaultFields []defaultField
+		// 静态可知的未被使用的源字段与未被赋值的目标字段，仅在需要时记录
+		var usedFromFields map[*structField]struct{}
+		if s.errorUnused || s.collectMetadata {
+			usedFromFields = make(map[*structField]struct{}, len(fromFields.flattened))
+		}
+		var unsetFields []*structField
 		for _, toField := range toFields.flattened {
+			defaultCaster, ok := getDefaultCaster(s, toField)
+			if !ok {
+				return nil, 0
+			}
 			fromField, ok := fromFields.byActualName[toField.name]
-			if !ok && toField.foldedName != "" && toField.foldedName != toField.name {
+			folded := false
+			if !ok && toField.foldedName != "" {
 				fromField, ok = fromFields.byFoldedName[toField.foldedName]
+				folded = ok
+			}
+			if !ok && toField.keyPath != nil {
+				first := toField.keyPath[0]
+				firstField, found := fromFields.byActualName[first]
+				if !found {
+					firstField, found = fromFields.byFoldedName[foldNameStr(first)]
+				}
+				if found {
+					if usedFromFields != nil {
+						usedFromFields[firstField] = struct{}{}
+					}
+					pathFields = append(pathFields, defaultField{
+						structField:   *toField,
+						defaultCaster: defaultCaster,
+					})
+					continue
+				}
 			}
 			if !ok {
 				if toField.isRequired {
 					return nil, 0
 				}
+				if defaultCaster != nil {
+					defaultFields = append(defaultFields, defaultField{
+						structField:   *toField,
+						defaultCaster: defaultCaster,
+					})
+				} else if s.errorUnset || s.collectMetadata {
+					unsetFields = append(unsetFields, toField)
+				}
 				continue
 			}
-			caster, fFlag := getCaster(s, fromField.typ, toField.typ)
+			if usedFromFields != nil {
+				usedFromFields[fromField] = struct{}{}
+			}
+			copyMode := toField.copyMode
+			if copyMode == copyDefault {
+				copyMode = fromField.copyMode
+			}
+			caster, fFlag := getCaster(s.forCopyMode(copyMode), fromField.typ, toField.typ)
 			if caster == nil {
 				return nil, 0
 			}
@@ -173,16 +486,77 @@ func getStructCaster(s *Scope, fromType, toType reflect.Type) (castFunc, uint8)
 				fromField:     *fromField,
 				caster:        caster,
 				fromIsNilable: isNilableType(fromField.typ),
+				defaultCaster: defaultCaster,
+				folded:        folded,
+			})
+			flag |= fFlag
+		}
+		// 静态可知的未被使用的源字段
+		var unused []string
+		if usedFromFields != nil {
+			for _, fromField := range fromFields.flattened {
+				if _, ok := usedFromFields[fromField]; !ok {
+					unused = append(unused, fromField.rawName)
+				}
+			}
+			if fromFields.remain != nil && toFields.remain == nil {
+				unused = append(unused, fromFields.remain.rawName)
+			}
+			sort.Strings(unused)
+		}
+		if toFields.remain != nil && fromFields.remain != nil {
+			caster, fFlag := getCaster(s, fromFields.remain.typ, toFields.remain.typ)
+			if caster == nil {
+				return nil, 0
+			}
+			metaFields = append(metaFields, metaField{
+				structField:   *toFields.remain,
+				fromField:     *fromFields.remain,
+				caster:        caster,
+				fromIsNilable: true,
 			})
 			flag |= fFlag
 		}
-		if len(metaFields) == 0 {
+		if len(metaFields) == 0 && len(defaultFields) == 0 && len(pathFields) == 0 && len(unused) == 0 && len(unsetFields) == 0 &&
+			setDefaults == nil && !s.collectMetadata {
 			return func(fromAddr, toAddr unsafe.Pointer) error {
 				return nil
 			}, 0
 		}
 		zeroPtr := getZeroPtr(toType)
 		return func(fromAddr, toAddr unsafe.Pointer) error {
+			md := s.getMetadata()
+			if setDefaults != nil {
+				setDefaults(toAddr)
+			}
+			// 未被使用的源字段与未被赋值的字段，包括嵌套结构体里的，仅在 s.errorUnused、s.errorUnset 时记录
+			var sc strictCollector
+			if s.errorUnused {
+				sc.unused = append(sc.unused, unused...)
+			}
+			for _, field := range unsetFields {
+				if !isUnsetField(field, nil, setDefaults, toAddr) {
+					continue
+				}
+				if s.errorUnset {
+					sc.unset = append(sc.unset, field.rawName)
+				}
+				if md != nil {
+					md.addUnset(field.rawName)
+				}
+			}
+			if md != nil {
+				for _, name := range unused {
+					md.addUnused(name)
+				}
+			}
+			for i := range defaultFields {
+				field := &defaultFields[i]
+				if err := setDefault(&field.structField, field.defaultCaster, toAddr); err != nil {
+					typedMemMove(typePtr(toType), toAddr, zeroPtr)
+					return err
+				}
+			}
 			for i := range metaFields {
 				field := &metaFields[i]
 				fromFieldAddr := field.fromField.getAddr(fromAddr, false)
@@ -191,13 +565,76 @@ func getStructCaster(s *Scope, fromType, toType reflect.Type) (castFunc, uint8)
 						typedMemMove(typePtr(toType), toAddr, zeroPtr)
 						return NilPtrErr
 					}
+					if (s.errorUnset || md != nil) && isUnsetField(&field.structField, field.defaultCaster, setDefaults, toAddr) {
+						if s.errorUnset {
+							sc.unset = append(sc.unset, field.rawName)
+						}
+						if md != nil {
+							md.addUnset(field.rawName)
+						}
+					}
+					if err := setDefault(&field.structField, field.defaultCaster, toAddr); err != nil {
+						typedMemMove(typePtr(toType), toAddr, zeroPtr)
+						return err
+					}
 					continue
 				}
-				if err := field.caster(fromFieldAddr, field.getAddr(toAddr, true)); err != nil {
+				if md != nil {
+					md.addKey(field.fromField.rawName, field.folded)
+					md.push(field.fromField.rawName)
+				}
+				err := field.caster(fromFieldAddr, field.getAddr(toAddr, true))
+				if md != nil {
+					md.pop()
+				}
+				if err != nil && !sc.merge(err, field.fromField.rawName, field.rawName) {
 					typedMemMove(typePtr(toType), toAddr, zeroPtr)
 					return err
 				}
 			}
+			if len(pathFields) > 0 {
+				root := reflect.NewAt(fromType, fromAddr).Elem()
+				for i := range pathFields {
+					field := &pathFields[i]
+					if md != nil {
+						md.push(field.name)
+					}
+					found, err := castPathValue(s, root, field.keyPath, &field.structField, toAddr)
+					if md != nil {
+						md.pop()
+					}
+					if err != nil && !sc.merge(err, field.name, field.rawName) {
+						typedMemMove(typePtr(toType), toAddr, zeroPtr)
+						return err
+					}
+					if found {
+						if md != nil {
+							md.addKey(field.name, false)
+						}
+						continue
+					}
+					if field.isRequired {
+						typedMemMove(typePtr(toType), toAddr, zeroPtr)
+						return requiredFieldNotMatchErr(toType, field.rawName)
+					}
+					if (s.errorUnset || md != nil) && isUnsetField(&field.structField, field.defaultCaster, setDefaults, toAddr) {
+						if s.errorUnset {
+							sc.unset = append(sc.unset, field.rawName)
+						}
+						if md != nil {
+							md.addUnset(field.rawName)
+						}
+					}
+					if err := setDefault(&field.structField, field.defaultCaster, toAddr); err != nil {
+						typedMemMove(typePtr(toType), toAddr, zeroPtr)
+						return err
+					}
+				}
+			}
+			if err := sc.err(fromType, toType); err != nil {
+				typedMemMove(typePtr(toType), toAddr, zeroPtr)
+				return err
+			}
 			return nil
 		}, flag
 	default:
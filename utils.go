@@ -7,6 +7,7 @@ package cast
 
 import (
 	"reflect"
+	"strconv"
 	"strings"
 	"sync"
 	"unicode"
@@ -15,6 +16,16 @@ import (
 )
 
 func isMemSame(s *Scope, fromType, toType reflect.Type) bool {
+	return isMemSameInner(s, fromType, toType, nil)
+}
+
+type typePair struct {
+	fromType reflect.Type
+	toType   reflect.Type
+}
+
+// isMemSameInner visiting 记录正在比较的结构体类型对，递归类型再次遇到时视为相同，由其余字段决定结果
+func isMemSameInner(s *Scope, fromType, toType reflect.Type, visiting map[typePair]struct{}) bool {
 	if fromType == toType {
 		return true
 	}
@@ -30,22 +41,22 @@ func isMemSame(s *Scope, fromType, toType reflect.Type) bool {
 		reflect.Float32, reflect.Float64, reflect.String, reflect.UnsafePointer:
 		return true
 	case reflect.Array:
-		return fromType.Len() == toType.Len() && isMemSame(s, fromType.Elem(), toType.Elem())
+		return fromType.Len() == toType.Len() && isMemSameInner(s, fromType.Elem(), toType.Elem(), visiting)
 	case reflect.Chan:
 		fromDir := fromType.ChanDir()
-		return (fromDir == reflect.BothDir || fromDir == toType.ChanDir()) && isMemSame(s, fromType.Elem(), toType.Elem())
+		return (fromDir == reflect.BothDir || fromDir == toType.ChanDir()) && isMemSameInner(s, fromType.Elem(), toType.Elem(), visiting)
 	case reflect.Func:
 		numIn, numOut := fromType.NumIn(), fromType.NumOut()
 		if numIn != toType.NumIn() || numOut != toType.NumOut() {
 			return false
 		}
 		for i := 0; i < numIn; i++ {
-			if !isMemSame(s, fromType.In(i), toType.In(i)) {
+			if !isMemSameInner(s, fromType.In(i), toType.In(i), visiting) {
 				return false
 			}
 		}
 		for i := 0; i < numOut; i++ {
-			if !isMemSame(s, fromType.Out(i), toType.Out(i)) {
+			if !isMemSameInner(s, fromType.Out(i), toType.Out(i), visiting) {
 				return false
 			}
 		}
@@ -54,10 +65,19 @@ func isMemSame(s *Scope, fromType, toType reflect.Type) bool {
 		// 空接口（eface）的第一个字是 *_type，所以只依赖具体类型；非空接口（iface）的第一个字是 *itab，而 itab 是 (interface type, concrete type) 的组合，里面还包含接口类型指针和该接口的方法表
 		return fromType == toType || (fromType.NumMethod() == 0 && toType.NumMethod() == 0)
 	case reflect.Map:
-		return isMemSame(s, fromType.Key(), toType.Key()) && isMemSame(s, fromType.Elem(), toType.Elem())
+		return isMemSameInner(s, fromType.Key(), toType.Key(), visiting) && isMemSameInner(s, fromType.Elem(), toType.Elem(), visiting)
 	case reflect.Pointer, reflect.Slice:
-		return isMemSame(s, fromType.Elem(), toType.Elem())
+		return isMemSameInner(s, fromType.Elem(), toType.Elem(), visiting)
 	case reflect.Struct:
+		pair := typePair{fromType, toType}
+		if _, ok := visiting[pair]; ok {
+			return true
+		}
+		if visiting == nil {
+			visiting = make(map[typePair]struct{})
+		}
+		visiting[pair] = struct{}{}
+		defer delete(visiting, pair)
 		n := fromType.NumField()
 		if n != toType.NumField() {
 			return false
@@ -82,7 +102,7 @@ func isMemSame(s *Scope, fromType, toType reflect.Type) bool {
 			if !(fromName == toName || foldNameStr(fromName) == foldNameStr(toName)) {
 				return false
 			}
-			if !isMemSame(s, fromField.Type, toField.Type) {
+			if !isMemSameInner(s, fromField.Type, toField.Type, visiting) {
 				return false
 			}
 		}
@@ -96,12 +116,17 @@ func getFieldName(field *reflect.StructField) (string, bool) {
 	if castTag := field.Tag.Get("cast"); castTag == "-" {
 		return "", true
 	} else if castTag != "" {
-		return strings.Split(castTag, ",")[0], false
+		if name := strings.Split(castTag, ",")[0]; name != "" {
+			return name, false
+		}
 	} else if jsonTag := field.Tag.Get("json"); jsonTag == "-" {
 		return "", false
 	} else if jsonTag != "" {
-		return strings.Split(jsonTag, ",")[0], false
+		if name := strings.Split(jsonTag, ",")[0]; name != "" {
+			return name, false
+		}
 	}
+	// tag 里只配置了 options 时，使用字段名
 	return field.Name, false
 }
 
@@ -147,6 +172,10 @@ func isRefAble(s *Scope, fromType, toType reflect.Type) bool {
 	if s.deepCopy {
 		return false
 	}
+	if s.deepMapping && hasEmptyInterface(toType, make(map[reflect.Type]struct{})) {
+		// 空接口里可能存放着需要递归转换的值
+		return false
+	}
 	if fromType == toType {
 		return true
 	}
@@ -248,6 +277,49 @@ func isRefType(typ reflect.Type) bool {
 	}
 }
 
+var deepFieldCache sync.Map
+
+// hasDeepField 类型里（包括嵌套的结构体、数组、slice、指针与 map 的元素）是否有配置了 deep 的字段
+func hasDeepField(s *Scope, typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.Array, reflect.Struct, reflect.Slice, reflect.Pointer, reflect.Map:
+	default:
+		return false
+	}
+	key := fieldCacheKey{
+		castUnexported: s.castUnexported,
+		typ:            typ,
+	}
+	if v, ok := deepFieldCache.Load(key); ok {
+		return v.(bool)
+	}
+	v, _ := deepFieldCache.LoadOrStore(key, hasDeepFieldInner(s, typ, nil))
+	return v.(bool)
+}
+
+func hasDeepFieldInner(s *Scope, typ reflect.Type, visited map[reflect.Type]struct{}) bool {
+	switch typ.Kind() {
+	case reflect.Array, reflect.Slice, reflect.Pointer, reflect.Map:
+		return hasDeepFieldInner(s, typ.Elem(), visited)
+	case reflect.Struct:
+		if _, ok := visited[typ]; ok {
+			return false
+		}
+		if visited == nil {
+			visited = make(map[reflect.Type]struct{})
+		}
+		visited[typ] = struct{}{}
+		for _, field := range getAllFields(s, typ).flattened {
+			if field.copyMode == copyDeep || hasDeepFieldInner(s, field.typ, visited) {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
 func isPtrType(typ reflect.Type) bool {
 	switch typ.Kind() {
 	// chan、map、func 其实就是一个指针
@@ -317,6 +389,132 @@ func makeSlice(elemType reflect.Type, len, cap int) slice {
 	}
 }
 
+func isMemZero(addr unsafe.Pointer, size uintptr) bool {
+	for _, b := range unsafe.Slice((*byte)(addr), size) {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// getOmitChecker 获取判断字段值是否应被忽略的函数，omitEmpty 与 omitZero 均为 false 时返回 nil
+func getOmitChecker(typ reflect.Type, omitEmpty, omitZero bool) func(addr unsafe.Pointer) bool {
+	var isEmpty, isZero func(addr unsafe.Pointer) bool
+	if omitEmpty {
+		isEmpty = getEmptyChecker(typ)
+	}
+	if omitZero {
+		isZero = getZeroChecker(typ)
+	}
+	if isEmpty == nil {
+		return isZero
+	}
+	if isZero == nil {
+		return isEmpty
+	}
+	return func(addr unsafe.Pointer) bool {
+		return isEmpty(addr) || isZero(addr)
+	}
+}
+
+// getEmptyChecker 与 encoding/json 的 omitempty 规则一致，结构体永远不为空，此时返回 nil
+func getEmptyChecker(typ reflect.Type) func(addr unsafe.Pointer) bool {
+	switch typ.Kind() {
+	case reflect.Array:
+		if typ.Len() != 0 {
+			return nil
+		}
+		return func(addr unsafe.Pointer) bool {
+			return true
+		}
+	case reflect.Map:
+		return func(addr unsafe.Pointer) bool {
+			return len(*(*map[any]any)(addr)) == 0
+		}
+	case reflect.Slice:
+		return func(addr unsafe.Pointer) bool {
+			return (*slice)(addr).len == 0
+		}
+	case reflect.String:
+		return func(addr unsafe.Pointer) bool {
+			return (*str)(addr).len == 0
+		}
+	case reflect.Bool:
+		return func(addr unsafe.Pointer) bool {
+			return !*(*bool)(addr)
+		}
+	case reflect.Float32:
+		return func(addr unsafe.Pointer) bool {
+			return *(*float32)(addr) == 0
+		}
+	case reflect.Float64:
+		return func(addr unsafe.Pointer) bool {
+			return *(*float64)(addr) == 0
+		}
+	case reflect.Chan, reflect.Func, reflect.Interface, reflect.Pointer, reflect.UnsafePointer:
+		return func(addr unsafe.Pointer) bool {
+			return *(*unsafe.Pointer)(addr) == nil
+		}
+	case reflect.Struct:
+		return nil
+	default:
+		size := typ.Size()
+		return func(addr unsafe.Pointer) bool {
+			return isMemZero(addr, size)
+		}
+	}
+}
+
+// getZeroChecker 与 encoding/json 的 omitzero 规则一致，实现了 IsZero() bool 方法时使用该方法判断
+func getZeroChecker(typ reflect.Type) func(addr unsafe.Pointer) bool {
+	if typ.Implements(isZeroerType) {
+		isNilable := isNilableType(typ)
+		return func(addr unsafe.Pointer) bool {
+			v := reflect.NewAt(typ, addr).Elem()
+			if isNilable && v.IsNil() {
+				return true
+			}
+			return v.Interface().(interface{ IsZero() bool }).IsZero()
+		}
+	}
+	if reflect.PointerTo(typ).Implements(isZeroerType) {
+		return func(addr unsafe.Pointer) bool {
+			return reflect.NewAt(typ, addr).Interface().(interface{ IsZero() bool }).IsZero()
+		}
+	}
+	switch typ.Kind() {
+	case reflect.String:
+		return func(addr unsafe.Pointer) bool {
+			return (*str)(addr).len == 0
+		}
+	case reflect.Slice, reflect.Map, reflect.Chan, reflect.Func, reflect.Interface, reflect.Pointer, reflect.UnsafePointer:
+		return func(addr unsafe.Pointer) bool {
+			return *(*unsafe.Pointer)(addr) == nil
+		}
+	case reflect.Array, reflect.Struct:
+		// 可能包含字符串等，不能直接比较内存
+		return func(addr unsafe.Pointer) bool {
+			return reflect.NewAt(typ, addr).Elem().IsZero()
+		}
+	default:
+		size := typ.Size()
+		return func(addr unsafe.Pointer) bool {
+			return isMemZero(addr, size)
+		}
+	}
+}
+
+// cloneString 拷贝字符串的底层内存，避免零拷贝转换后修改只读内存（如 tag 字面量）
+func cloneString(s string) string {
+	if len(s) == 0 {
+		return ""
+	}
+	b := make([]byte, len(s))
+	copy(b, s)
+	return toString(b)
+}
+
 type str struct {
 	data unsafe.Pointer
 	len  int
@@ -378,12 +576,20 @@ func getZeroPtr(typ reflect.Type) unsafe.Pointer {
 }
 
 type structField struct {
-	rawName    string // 原始字段名，仅打error用
+	rawName    string // 原始字段路径，如 DB.Host，仅打error用
 	name       string // 一定非空
 	foldedName string // 可能为空，为空说明是名称重复
 	offset     uintptr
 	typ        reflect.Type
 	isRequired bool
+	hasDefault bool     // 是否配置了默认值
+	defaultVal string   // 默认值的字面量，由作用域里 string 的转换器转为字段类型
+	omitEmpty  bool     // 结构体转 map 时，忽略空值（false、0、nil、空字符串/切片/map）
+	omitZero   bool     // 结构体转 map 时，忽略零值，优先使用 IsZero() 方法判断
+	keyPath    []string // cast tag 的名称里含有 . 时，按路径访问嵌套的 map/结构体
+	hasIndex   bool     // 是否配置了 index，与 slice/array 按位置互转时使用
+	index      int      // 配置的 index 不合法时为 -1
+	copyMode   uint8    // 字段的拷贝方式，由 shallow、deep 选项指定
 	// 嵌套结构体指针相关字段
 	parent        *structField
 	parentElemTyp reflect.Type
@@ -416,6 +622,7 @@ type structFields struct {
 	flattened    []*structField
 	byActualName map[string]*structField
 	byFoldedName map[string]*structField
+	remain       *structField // 接收未匹配 key 的 map[string]V 字段，不参与字段匹配
 }
 
 var fieldCache sync.Map
@@ -434,15 +641,18 @@ func getAllFields(s *Scope, typ reflect.Type) structFields {
 	if f, ok := fieldCache.Load(key); ok {
 		return f.(structFields)
 	}
-	f, _ := fieldCache.LoadOrStore(key, getAllFieldsInner(s, typ, 0, nil, make(map[reflect.Type]struct{})))
+	f, _ := fieldCache.LoadOrStore(key, getAllFieldsInner(s, typ, 0, nil, "", make(map[reflect.Type]struct{})))
 	return f.(structFields)
 }
 
-func getAllFieldsInner(s *Scope, typ reflect.Type, offset uintptr, parent *structField, visited map[reflect.Type]struct{}) structFields {
+// pathPrefix 为展开的结构体字段的路径前缀，仅用于 rawName
+func getAllFieldsInner(s *Scope, typ reflect.Type, offset uintptr, parent *structField, pathPrefix string, visited map[reflect.Type]struct{}) structFields {
 	if _, v := visited[typ]; v {
 		return structFields{}
 	}
+	// 仅用于检测环形依赖，同一个类型可以在不同路径上出现（如多个带不同前缀的内联字段）
 	visited[typ] = struct{}{}
+	defer delete(visited, typ)
 
 	n := typ.NumField()
 	fields := make([]*structField, 0, n)
@@ -452,6 +662,7 @@ func getAllFieldsInner(s *Scope, typ reflect.Type, offset uintptr, parent *struc
 	foldedNameCandidateMap := make(map[string][]*structField, n)
 	anonymousNameMap := make(map[string][]*structField)
 	anonymousFoldedNameMap := make(map[string][]*structField)
+	var remain, anonymousRemain *structField
 
 	for i := 0; i < n; i++ {
 		reflectField := typ.Field(i)
@@ -459,7 +670,7 @@ func getAllFieldsInner(s *Scope, typ reflect.Type, offset uintptr, parent *struc
 			continue
 		}
 		field := &structField{
-			rawName: reflectField.Name,
+			rawName: pathPrefix + reflectField.Name,
 			offset:  offset + reflectField.Offset,
 			typ:     reflectField.Type,
 		}
@@ -467,38 +678,86 @@ func getAllFieldsInner(s *Scope, typ reflect.Type, offset uintptr, parent *struc
 			field.parent = parent
 			field.parentElemTyp = parent.typ.Elem()
 		}
+		// inline: 将结构体字段的字段展开到当前结构体；nested: 不展开匿名结构体字段；prefix: 展开并给字段名加上前缀
+		var inline, nested, isRemain bool
+		var prefix string
 		if castTag := typ.Field(i).Tag.Get("cast"); castTag == "-" {
 			continue
 		} else if castTag != "" {
 			values := strings.Split(castTag, ",")
 			field.name = values[0]
+			if strings.Contains(field.name, ".") {
+				field.keyPath = strings.Split(field.name, ".")
+			}
 			for _, value := range values[1:] {
-				switch value {
-				case "required":
+				switch {
+				case value == "required":
 					field.isRequired = true
-				default:
-					break
+				case value == "inline", value == "squash":
+					inline = true
+				case value == "nested":
+					nested = true
+				case value == "remain":
+					isRemain = true
+				case strings.HasPrefix(value, "prefix="):
+					inline = true
+					prefix = strings.TrimPrefix(value, "prefix=")
+				case value == "omitempty":
+					field.omitEmpty = true
+				case value == "omitzero":
+					field.omitZero = true
+				case value == "shallow":
+					field.copyMode = copyShallow
+				case value == "deep":
+					field.copyMode = copyDeep
+				case strings.HasPrefix(value, "index="):
+					// index 不合法时记为 -1，按位置转换时报错
+					field.hasIndex = true
+					field.index = -1
+					if index, err := strconv.Atoi(strings.TrimPrefix(value, "index=")); err == nil && index >= 0 {
+						field.index = index
+					}
+				case strings.HasPrefix(value, "default="):
+					field.hasDefault = true
+					field.defaultVal = strings.TrimPrefix(value, "default=")
 				}
 			}
 		} else if jsonTag := typ.Field(i).Tag.Get("json"); jsonTag != "" && jsonTag != "-" {
-			field.name = strings.Split(jsonTag, ",")[0]
+			values := strings.Split(jsonTag, ",")
+			field.name = values[0]
+			for _, value := range values[1:] {
+				switch value {
+				case "omitempty":
+					field.omitEmpty = true
+				case "omitzero":
+					field.omitZero = true
+				}
+			}
 		}
-		if field.name == "" && reflectField.Anonymous {
+		if inline || field.name == "" && reflectField.Anonymous && !nested {
+			var subFields structFields
+			isStruct := true
 			if field.typ.Kind() == reflect.Struct {
-				subFields := getAllFieldsInner(s, field.typ, field.offset, nil, visited)
-				for _, anonymousField := range subFields.flattened {
-					anonymousFields = append(anonymousFields, anonymousField)
-					name, foldedName := anonymousField.name, anonymousField.foldedName
-					anonymousNameMap[name] = append(anonymousNameMap[name], anonymousField)
-					if anonymousField.foldedName == "" {
-						continue
-					}
-					anonymousFoldedNameMap[foldedName] = append(anonymousFoldedNameMap[foldedName], anonymousField)
-				}
-				continue
+				subFields = getAllFieldsInner(s, field.typ, field.offset, parent, field.rawName+".", visited)
 			} else if field.typ.Kind() == reflect.Ptr && field.typ.Elem().Kind() == reflect.Struct {
-				subFields := getAllFieldsInner(s, field.typ.Elem(), 0, field, visited)
+				subFields = getAllFieldsInner(s, field.typ.Elem(), 0, field, field.rawName+".", visited)
+			} else {
+				isStruct = false
+			}
+			if isStruct {
+				if prefix == "" && anonymousRemain == nil {
+					anonymousRemain = subFields.remain
+				}
 				for _, anonymousField := range subFields.flattened {
+					if prefix != "" {
+						anonymousField.name = prefix + anonymousField.name
+						if anonymousField.keyPath != nil || strings.Contains(prefix, ".") {
+							anonymousField.keyPath = strings.Split(anonymousField.name, ".")
+						}
+						if anonymousField.foldedName != "" {
+							anonymousField.foldedName = foldNameStr(anonymousField.name)
+						}
+					}
 					anonymousFields = append(anonymousFields, anonymousField)
 					name, foldedName := anonymousField.name, anonymousField.foldedName
 					anonymousNameMap[name] = append(anonymousNameMap[name], anonymousField)
@@ -510,6 +769,10 @@ func getAllFieldsInner(s *Scope, typ reflect.Type, offset uintptr, parent *struc
 				continue
 			}
 		}
+		if isRemain && remain == nil && field.typ.Kind() == reflect.Map && field.typ.Key().Kind() == reflect.String {
+			remain = field
+			continue
+		}
 		if field.name == "" {
 			field.name = reflectField.Name
 		}
@@ -533,11 +796,14 @@ func getAllFieldsInner(s *Scope, typ reflect.Type, offset uintptr, parent *struc
 		nameMap[name] = anonymousField
 		fields = append(fields, anonymousField)
 		foldedName := anonymousField.foldedName
-		if _, ok := foldedNameMap[foldedName]; ok && len(anonymousFoldedNameMap[foldedName]) != 1 {
+		if _, ok := foldedNameCandidateMap[foldedName]; ok || len(anonymousFoldedNameMap[foldedName]) != 1 {
 			anonymousField.foldedName = ""
 			continue
 		}
 		foldedNameMap[foldedName] = anonymousField
 	}
-	return structFields{fields, nameMap, foldedNameMap}
+	if remain == nil {
+		remain = anonymousRemain
+	}
+	return structFields{fields, nameMap, foldedNameMap, remain}
 }